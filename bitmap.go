@@ -0,0 +1,20 @@
+package winmenu
+
+import "github.com/kroppt/winmenu/internal/win"
+
+var (
+	modgdi32         = win.Gdi32
+	procDeleteObject = modgdi32.NewProc("DeleteObject")
+)
+
+// HIcon is a handle to an icon.
+// (https://docs.microsoft.com/en-us/windows/desktop/WinProg/windows-data-types#HICON)
+type HIcon uintptr
+
+// Delete releases the bitmap. A bitmap must not be deleted while a menu item
+// still refers to it.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/wingdi/nf-wingdi-deleteobject)
+func (hbm HBitmap) Delete() (ok bool) {
+	ret, _, err := procDeleteObject.Call(uintptr(hbm))
+	return check("DeleteObject", ret != 0, err)
+}
@@ -0,0 +1,200 @@
+package winmenu
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/kroppt/winmenu/internal/win"
+)
+
+var (
+	modntdll                 = win.Ntdll
+	procRtlGetVersion        = modntdll.NewProc("RtlGetVersion")
+	procGetDeviceCaps        = modgdi32.NewProc("GetDeviceCaps")
+	procGetDC                = moduser32.NewProc("GetDC")
+	procReleaseDC            = moduser32.NewProc("ReleaseDC")
+	procSystemParametersInfo = moduser32.NewProc("SystemParametersInfoW")
+)
+
+const (
+	logPixelsX          = 88
+	spiGetHighContrast  = 0x0042
+	hcfHighContrastOn   = 0x00000001
+	personalizeKey      = `Software\Microsoft\Windows\CurrentVersion\Themes\Personalize`
+	appsUseLightTheme   = "AppsUseLightTheme"
+	diagnosticsItemID   = 1
+	diagnosticsItemText = "Diagnostics עברית é \U0001F600\tCtrl+D"
+)
+
+type osVersionInfo struct {
+	size        uint32
+	major       uint32
+	minor       uint32
+	build       uint32
+	platformID  uint32
+	servicePack [128]uint16
+}
+
+type highContrast struct {
+	size          uint32
+	flags         uint32
+	defaultScheme *uint16
+}
+
+// Diagnostics describes the environment the package runs in and the result of
+// a self-test of the menu functions, for attaching to bug reports.
+type Diagnostics struct {
+	// The Windows version, such as "10.0.22631".
+	Version string
+	// The system DPI; 96 is 100% scaling.
+	DPI int
+	// Whether apps use the dark theme.
+	DarkMode bool
+	// Whether a high contrast theme is on.
+	HighContrast bool
+	// The failed self-test steps, empty if all passed.
+	Failures []string
+}
+
+// String formats the diagnostics as a short plain text report.
+func (d Diagnostics) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Windows %s, %d DPI, dark mode %t, high contrast %t\n",
+		d.Version, d.DPI, d.DarkMode, d.HighContrast)
+	if len(d.Failures) == 0 {
+		b.WriteString("all menu checks passed\n")
+	}
+	for _, f := range d.Failures {
+		b.WriteString("FAIL " + f + "\n")
+	}
+	return b.String()
+}
+
+// RunDiagnostics collects information about the environment and runs a
+// self-test that creates a popup menu, inserts, queries, changes, and deletes an
+// item, and destroys the menu, recording each step that fails. It recovers the
+// panics of StrictPanic, so it can be run in any strict mode, and it does not
+// show any menu.
+func RunDiagnostics() Diagnostics {
+	d := Diagnostics{
+		Version:      windowsVersion(),
+		DPI:          systemDPI(),
+		DarkMode:     darkMode(),
+		HighContrast: highContrastOn(),
+	}
+	step := func(name string, f func() bool) (ok bool) {
+		defer func() {
+			if r := recover(); r != nil {
+				d.Failures = append(d.Failures, fmt.Sprintf("%s: %v", name, r))
+				ok = false
+			}
+		}()
+		if !f() {
+			d.Failures = append(d.Failures, name)
+			return false
+		}
+		return true
+	}
+	var hmenu HMenu
+	if !step("CreatePopupMenu", func() (ok bool) {
+		hmenu, ok = CreatePopupMenu()
+		return ok
+	}) {
+		return d
+	}
+	if step("InsertMenuItem", func() bool {
+		mii := NewMenuItemInfo()
+		mii.SetID(diagnosticsItemID)
+		mii.SetAsString(diagnosticsItemText)
+		return hmenu.InsertMenuItem(0, true, mii)
+	}) {
+		step("GetMenuItemInfo round trip", func() bool {
+			mii := NewMenuItemInfo()
+			mii.SetMask(MIIM_STRING | MIIM_ID | MIIM_FTYPE)
+			return hmenu.GetMenuItemInfo(diagnosticsItemID, false, mii) &&
+				mii.ID() == diagnosticsItemID && mii.Text() == diagnosticsItemText
+		})
+		step("GetMenuString round trip", func() bool {
+			text, err := hmenu.ItemString(0, true)
+			return err == nil && text == diagnosticsItemText
+		})
+		step("Long label round trip", func() bool {
+			// A long path with combining characters and bidirectional marks.
+			label := strings.Repeat("\\Cafe\u0301\u200f", 4000)
+			mii := NewMenuItemInfo()
+			mii.SetAsString(label)
+			if !hmenu.SetMenuItemInfo(diagnosticsItemID, false, mii) {
+				return false
+			}
+			text, err := hmenu.ItemString(diagnosticsItemID, false)
+			return err == nil && text == label
+		})
+		step("CheckMenuItem", func() bool {
+			_, ok := hmenu.CheckItem(diagnosticsItemID, false, true)
+			state, _ := hmenu.State(diagnosticsItemID, MF_BYCOMMAND)
+			return ok && state&MF_CHECKED != 0
+		})
+		step("EnableMenuItem", func() bool {
+			_, ok := hmenu.EnableItem(diagnosticsItemID, false, MF_GRAYED)
+			state, _ := hmenu.State(diagnosticsItemID, MF_BYCOMMAND)
+			return ok && state&MF_GRAYED != 0
+		})
+		step("DeleteMenu", func() bool {
+			return hmenu.DeleteMenu(diagnosticsItemID, false)
+		})
+	}
+	step("DestroyMenu", hmenu.Destroy)
+	return d
+}
+
+// windowsVersion returns the version reported by RtlGetVersion, which, unlike
+// GetVersionEx, is not affected by the application manifest.
+func windowsVersion() string {
+	vi := osVersionInfo{}
+	vi.size = uint32(unsafe.Sizeof(vi))
+	if ret, _, _ := procRtlGetVersion.Call(uintptr(unsafe.Pointer(&vi))); ret != 0 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%d.%d.%d", vi.major, vi.minor, vi.build)
+}
+
+func systemDPI() int {
+	hdc, _, _ := procGetDC.Call(0)
+	if hdc == 0 {
+		return 0
+	}
+	defer procReleaseDC.Call(0, hdc)
+	dpi, _, _ := procGetDeviceCaps.Call(hdc, logPixelsX)
+	return int(dpi)
+}
+
+func darkMode() bool {
+	key, err := syscall.UTF16PtrFromString(personalizeKey)
+	if err != nil {
+		return false
+	}
+	var h syscall.Handle
+	if syscall.RegOpenKeyEx(syscall.HKEY_CURRENT_USER, key, 0, syscall.KEY_READ, &h) != nil {
+		return false
+	}
+	defer syscall.RegCloseKey(h)
+	name, err := syscall.UTF16PtrFromString(appsUseLightTheme)
+	if err != nil {
+		return false
+	}
+	var light, typ uint32
+	size := uint32(unsafe.Sizeof(light))
+	if syscall.RegQueryValueEx(h, name, nil, &typ, (*byte)(unsafe.Pointer(&light)), &size) != nil {
+		return false
+	}
+	return typ == syscall.REG_DWORD && light == 0
+}
+
+func highContrastOn() bool {
+	hc := highContrast{}
+	hc.size = uint32(unsafe.Sizeof(hc))
+	ret, _, _ := procSystemParametersInfo.Call(spiGetHighContrast, uintptr(hc.size), uintptr(unsafe.Pointer(&hc)), 0)
+	return ret != 0 && hc.flags&hcfHighContrastOn != 0
+}
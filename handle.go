@@ -0,0 +1,52 @@
+package winmenu
+
+// FromRaw wraps a raw HMENU, such as one created by another library. The
+// handle stays owned by its creator; see the package documentation.
+func FromRaw(h uintptr) HMenu {
+	return HMenu(h)
+}
+
+// Raw returns the raw HMENU.
+func (hMenu HMenu) Raw() uintptr {
+	return uintptr(hMenu)
+}
+
+// BitmapFromRaw wraps a raw HBITMAP.
+func BitmapFromRaw(h uintptr) HBitmap {
+	return HBitmap(h)
+}
+
+// Raw returns the raw HBITMAP.
+func (hbm HBitmap) Raw() uintptr {
+	return uintptr(hbm)
+}
+
+// IconFromRaw wraps a raw HICON.
+func IconFromRaw(h uintptr) HIcon {
+	return HIcon(h)
+}
+
+// Raw returns the raw HICON.
+func (hicon HIcon) Raw() uintptr {
+	return uintptr(hicon)
+}
+
+// WindowFromRaw wraps a raw HWND.
+func WindowFromRaw(h uintptr) HWnd {
+	return HWnd(h)
+}
+
+// Raw returns the raw HWND.
+func (hwnd HWnd) Raw() uintptr {
+	return uintptr(hwnd)
+}
+
+// InstanceFromRaw wraps a raw HINSTANCE or HMODULE.
+func InstanceFromRaw(h uintptr) HInstance {
+	return HInstance(h)
+}
+
+// Raw returns the raw HINSTANCE.
+func (hinst HInstance) Raw() uintptr {
+	return uintptr(hinst)
+}
@@ -0,0 +1,51 @@
+// Package win loads the system DLLs shared by winmenu and its subpackages, so
+// that each DLL is loaded once and every procedure reports a missing export
+// the same way.
+package win
+
+import "syscall"
+
+// The system DLLs used by winmenu.
+var (
+	User32   = &DLL{syscall.NewLazyDLL("user32.dll")}
+	Gdi32    = &DLL{syscall.NewLazyDLL("gdi32.dll")}
+	Kernel32 = &DLL{syscall.NewLazyDLL("kernel32.dll")}
+	Shell32  = &DLL{syscall.NewLazyDLL("shell32.dll")}
+	Ntdll    = &DLL{syscall.NewLazyDLL("ntdll.dll")}
+	Advapi32 = &DLL{syscall.NewLazyDLL("advapi32.dll")}
+	Powrprof = &DLL{syscall.NewLazyDLL("powrprof.dll")}
+	Winspool = &DLL{syscall.NewLazyDLL("winspool.drv")}
+)
+
+// Check is called by Proc.Call with the name of a procedure that the DLL
+// does not export. The winmenu package sets it to its strict mode check.
+var Check = func(api string, ok bool, err error) bool {
+	return ok
+}
+
+// DLL is a lazily loaded system DLL.
+type DLL struct {
+	*syscall.LazyDLL
+}
+
+// NewProc returns the named procedure of the DLL.
+func (d *DLL) NewProc(name string) *Proc {
+	return &Proc{d.LazyDLL.NewProc(name)}
+}
+
+// Proc is a procedure of a system DLL. Unlike syscall.LazyProc, whose methods
+// it otherwise shares, its Call does not panic if the DLL does not export the
+// procedure. It fails through Check instead and returns the error from Find.
+type Proc struct {
+	*syscall.LazyProc
+}
+
+// Call calls the procedure as syscall.LazyProc.Call does, after checking that
+// it exists.
+func (p *Proc) Call(a ...uintptr) (r1, r2 uintptr, err error) {
+	if err := p.Find(); err != nil {
+		Check(p.Name, false, err)
+		return 0, 0, err
+	}
+	return p.LazyProc.Call(a...)
+}
@@ -0,0 +1,48 @@
+package winmenu
+
+import (
+	"testing"
+	"unicode/utf16"
+	"unsafe"
+)
+
+// readUTF16 decodes the NUL-terminated UTF-16 string at p.
+func readUTF16(p *uint16) string {
+	var s []uint16
+	for ; *p != 0; p = (*uint16)(unsafe.Add(unsafe.Pointer(p), 2)) {
+		s = append(s, *p)
+	}
+	return string(utf16.Decode(s))
+}
+
+func TestLabelUTF16(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"&File", "&File"},
+		{"Open\tCtrl+O", "Open\tCtrl+O"},
+		{"a\x00b", "a"},
+		{"\x00", ""},
+		{"Smile 😀", "Smile 😀"},
+		{"𝄞\x00𝄞", "𝄞"},
+		{"é", "é"},
+		{"\xff", "�"},
+	}
+	for _, tt := range tests {
+		if got := readUTF16(labelUTF16(tt.in)); got != tt.want {
+			t.Errorf("labelUTF16(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLabelUTF16Surrogates(t *testing.T) {
+	p := labelUTF16("😀")
+	got := unsafe.Slice(p, 3)
+	want := []uint16{0xD83D, 0xDE00, 0}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("labelUTF16(%q) = %#x, want %#x", "😀", got, want)
+		}
+	}
+}
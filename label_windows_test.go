@@ -0,0 +1,96 @@
+//go:build windows
+
+package winmenu
+
+import (
+	"strings"
+	"testing"
+)
+
+// roundTripLabels are labels that Windows should store and return unchanged.
+var roundTripLabels = []string{
+	"",
+	"&File",
+	"Open\tCtrl+O",
+	"Smile 😀 𝄞",
+	"\u202eRTL override\u202c and \u200fעברית",
+	"e\u0301 a\u0308\u0304 n\u0303",
+	"\U0001F469\u200d\U0001F467",
+	strings.Repeat("Long label ", 1000),
+	strings.Repeat("😀", 5000),
+}
+
+func TestLabelRoundTrip(t *testing.T) {
+	hmenu, ok := CreatePopupMenu()
+	if !ok {
+		t.Fatal("CreatePopupMenu failed")
+	}
+	defer hmenu.Destroy()
+	for i, label := range roundTripLabels {
+		id := uint32(100 + i)
+		mii := NewMenuItemInfo()
+		mii.SetAsString(label)
+		mii.SetID(id)
+		if !hmenu.InsertMenuItem(uint32(i), true, mii) {
+			t.Fatalf("InsertMenuItem of label %d failed", i)
+		}
+	}
+	read := func(when string) {
+		for i, label := range roundTripLabels {
+			id := uint32(100 + i)
+			mii := NewMenuItemInfo()
+			mii.SetMask(MIIM_STRING | MIIM_FTYPE)
+			if !hmenu.GetMenuItemInfo(id, false, mii) {
+				t.Errorf("%s: GetMenuItemInfo of label %d failed", when, i)
+			} else if got := mii.Text(); got != label {
+				t.Errorf("%s: GetMenuItemInfo of label %d = %.40q (%d bytes), want %.40q (%d bytes)",
+					when, i, got, len(got), label, len(label))
+			}
+			if got, err := hmenu.ItemString(id, false); err != nil {
+				t.Errorf("%s: ItemString of label %d failed: %v", when, i, err)
+			} else if got != label {
+				t.Errorf("%s: ItemString of label %d = %.40q (%d bytes), want %.40q (%d bytes)",
+					when, i, got, len(got), label, len(label))
+			}
+		}
+	}
+	read("after InsertMenuItem")
+	// Write every label back through SetMenuItemInfo, in reverse so that each
+	// item really changes, then restore them.
+	n := len(roundTripLabels)
+	for _, labels := range [][]string{reversed(roundTripLabels), roundTripLabels} {
+		for i, label := range labels {
+			mii := NewMenuItemInfo()
+			mii.SetAsString(label)
+			if !hmenu.SetMenuItemInfo(uint32(100+i), false, mii) {
+				t.Fatalf("SetMenuItemInfo of label %d of %d failed", i, n)
+			}
+		}
+	}
+	read("after SetMenuItemInfo")
+}
+
+func TestLabelTruncatedAtNUL(t *testing.T) {
+	hmenu, ok := CreatePopupMenu()
+	if !ok {
+		t.Fatal("CreatePopupMenu failed")
+	}
+	defer hmenu.Destroy()
+	mii := NewMenuItemInfo()
+	mii.SetAsString("before\x00after")
+	mii.SetID(1)
+	if !hmenu.InsertMenuItem(0, true, mii) {
+		t.Fatal("InsertMenuItem failed")
+	}
+	if got, err := hmenu.ItemString(1, false); err != nil || got != "before" {
+		t.Errorf("ItemString = %q, %v, want \"before\"", got, err)
+	}
+}
+
+func reversed(s []string) []string {
+	r := make([]string, len(s))
+	for i, v := range s {
+		r[len(s)-1-i] = v
+	}
+	return r
+}
@@ -0,0 +1,315 @@
+package menu
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/png"
+	"syscall"
+	"unsafe"
+
+	"github.com/kroppt/winmenu"
+	"github.com/kroppt/winmenu/internal/win"
+)
+
+var (
+	procCreateCompatibleDC = win.Gdi32.NewProc("CreateCompatibleDC")
+	procDeleteDC           = win.Gdi32.NewProc("DeleteDC")
+	procCreateBitmap       = win.Gdi32.NewProc("CreateBitmap")
+	procSelectObject       = win.Gdi32.NewProc("SelectObject")
+	procDeleteObject       = win.Gdi32.NewProc("DeleteObject")
+	procPatBlt             = win.Gdi32.NewProc("PatBlt")
+	procCreateFont         = win.Gdi32.NewProc("CreateFontW")
+	procSetTextColor       = win.Gdi32.NewProc("SetTextColor")
+	procSetBkMode          = win.Gdi32.NewProc("SetBkMode")
+	procCreateDIBSection   = win.Gdi32.NewProc("CreateDIBSection")
+	procGetSystemMetrics   = win.User32.NewProc("GetSystemMetrics")
+	procGdiFlush           = win.Gdi32.NewProc("GdiFlush")
+	procDrawIconEx         = win.User32.NewProc("DrawIconEx")
+	procDrawText           = win.User32.NewProc("DrawTextW")
+	procGetMenuState       = win.User32.NewProc("GetMenuState")
+)
+
+// Icon font face names that ship with Windows and contain menu glyphs.
+const (
+	// Windows 10 icon font.
+	SegoeMDL2Assets = "Segoe MDL2 Assets"
+	// Windows 11 icon font.
+	SegoeFluentIcons = "Segoe Fluent Icons"
+)
+
+// Code points of commonly used glyphs in SegoeMDL2Assets and
+// SegoeFluentIcons.
+const (
+	GlyphCheckMark rune = 0xE73E
+	GlyphPin       rune = 0xE718
+	GlyphUnpin     rune = 0xE77A
+	GlyphStar      rune = 0xE734
+	GlyphRadioDot  rune = 0xE915
+)
+
+const (
+	whiteness          = 0x00FF0062
+	transparent        = 1
+	fwNormal           = 400
+	defaultCharset     = 1
+	nonAntialiasedQual = 3
+	dtCenter           = 0x00000001
+	dtVCenter          = 0x00000004
+	dtSingleLine       = 0x00000020
+	dtNoPrefix         = 0x00000800
+	dibRGBColors       = 0
+	smCXSmIcon         = 49
+	diNormal           = 0x0003
+)
+
+type bitmapInfoHeader struct {
+	size          uint32
+	width         int32
+	height        int32
+	planes        uint16
+	bitCount      uint16
+	compression   uint32
+	sizeImage     uint32
+	xPelsPerMeter int32
+	yPelsPerMeter int32
+	clrUsed       uint32
+	clrImportant  uint32
+}
+
+// GlyphBitmap rasterizes the given glyph of an icon font (such as
+// SegoeMDL2Assets) into a monochrome bitmap the size of the system check mark,
+// suitable for SetCheckmark and SetUncheckmark. The caller owns the returned
+// bitmap and should release it with Delete.
+func GlyphBitmap(face string, glyph rune) (hbm winmenu.HBitmap, ok bool) {
+	cx, cy := winmenu.CheckMarkDimensions()
+	faceName, err := syscall.UTF16PtrFromString(face)
+	if err != nil {
+		return 0, false
+	}
+	text, err := syscall.UTF16FromString(string(glyph))
+	if err != nil {
+		return 0, false
+	}
+	hdc, _, _ := procCreateCompatibleDC.Call(0)
+	if hdc == 0 {
+		return 0, false
+	}
+	defer procDeleteDC.Call(hdc)
+	ret, _, _ := procCreateBitmap.Call(uintptr(cx), uintptr(cy), 1, 1, 0)
+	if ret == 0 {
+		return 0, false
+	}
+	hbm = winmenu.HBitmap(ret)
+	font, _, _ := procCreateFont.Call(uintptr(-cy), 0, 0, 0, fwNormal, 0, 0, 0,
+		defaultCharset, 0, 0, nonAntialiasedQual, 0, uintptr(unsafe.Pointer(faceName)))
+	if font == 0 {
+		hbm.Delete()
+		return 0, false
+	}
+	defer procDeleteObject.Call(font)
+	oldBitmap, _, _ := procSelectObject.Call(hdc, uintptr(hbm))
+	oldFont, _, _ := procSelectObject.Call(hdc, font)
+	// White is transparent and black takes the menu text color.
+	procPatBlt.Call(hdc, 0, 0, uintptr(cx), uintptr(cy), whiteness)
+	procSetTextColor.Call(hdc, 0)
+	procSetBkMode.Call(hdc, transparent)
+	rc := winmenu.Rect{Right: cx, Bottom: cy}
+	ret, _, _ = procDrawText.Call(hdc, uintptr(unsafe.Pointer(&text[0])), uintptr(len(text)-1),
+		uintptr(unsafe.Pointer(&rc)), dtCenter|dtVCenter|dtSingleLine|dtNoPrefix)
+	procSelectObject.Call(hdc, oldFont)
+	procSelectObject.Call(hdc, oldBitmap)
+	if ret == 0 {
+		hbm.Delete()
+		return 0, false
+	}
+	return hbm, true
+}
+
+// BitmapFromImage converts img into a 32-bit bitmap with premultiplied alpha,
+// which menus draw with transparency when used with SetAsBitmap. The caller
+// owns the returned bitmap and should release it with Delete.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/wingdi/nf-wingdi-createdibsection)
+func BitmapFromImage(img image.Image) (winmenu.HBitmap, error) {
+	b := img.Bounds()
+	if b.Empty() {
+		return 0, errors.New("winmenu: empty image")
+	}
+	// image.RGBA is already alpha-premultiplied.
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	bmi := bitmapInfoHeader{
+		width:    int32(b.Dx()),
+		height:   -int32(b.Dy()), // top-down
+		planes:   1,
+		bitCount: 32,
+	}
+	bmi.size = uint32(unsafe.Sizeof(bmi))
+	var bits unsafe.Pointer
+	ret, _, err := procCreateDIBSection.Call(0, uintptr(unsafe.Pointer(&bmi)), dibRGBColors,
+		uintptr(unsafe.Pointer(&bits)), 0, 0)
+	if ret == 0 {
+		return 0, err
+	}
+	dst := unsafe.Slice((*byte)(bits), len(rgba.Pix))
+	for i := 0; i < len(rgba.Pix); i += 4 {
+		dst[i+0] = rgba.Pix[i+2]
+		dst[i+1] = rgba.Pix[i+1]
+		dst[i+2] = rgba.Pix[i+0]
+		dst[i+3] = rgba.Pix[i+3]
+	}
+	return winmenu.HBitmap(ret), nil
+}
+
+// LoadBitmapFromPNG decodes PNG data into a menu item bitmap.
+func LoadBitmapFromPNG(data []byte) (winmenu.HBitmap, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	return BitmapFromImage(img)
+}
+
+// LoadBitmapFromICO decodes ICO data into a menu item bitmap, picking the image
+// in the icon that best fits the system small icon size.
+func LoadBitmapFromICO(data []byte) (winmenu.HBitmap, error) {
+	size, _, _ := procGetSystemMetrics.Call(smCXSmIcon)
+	img, err := decodeICO(data, int(size))
+	if err != nil {
+		return 0, err
+	}
+	return BitmapFromImage(img)
+}
+
+// IconSource produces menu icons on demand, such as an SVG renderer.
+type IconSource interface {
+	// Rasterize returns the icon rendered at sizePx by sizePx pixels for a
+	// display of the given DPI.
+	Rasterize(sizePx, dpi int) image.Image
+}
+
+// BitmapFromIconSource rasterizes src at the small icon size for the given DPI
+// and converts it with BitmapFromImage. Call it again with the new DPI when the
+// window's DPI changes to get a crisp replacement bitmap.
+func BitmapFromIconSource(src IconSource, dpi int) (winmenu.HBitmap, error) {
+	if dpi <= 0 {
+		dpi = 96
+	}
+	size := (16*dpi + 48) / 96
+	img := src.Rasterize(size, dpi)
+	if img == nil {
+		return 0, errors.New("winmenu: icon source returned no image")
+	}
+	return BitmapFromImage(img)
+}
+
+// BitmapFromIcon draws hicon at size by size pixels into a 32-bit bitmap with
+// premultiplied alpha. Icons without an alpha channel get their transparency
+// from the icon mask. The caller owns the returned bitmap and should release it
+// with Delete.
+func BitmapFromIcon(hicon winmenu.HIcon, size int) (hbm winmenu.HBitmap, ok bool) {
+	if size <= 0 {
+		return 0, false
+	}
+	// Drawing onto black and onto white recovers the alpha of both alpha
+	// blended and masked icons.
+	black, blackBits, ok := drawIconDIB(hicon, size, 0x00)
+	if !ok {
+		return 0, false
+	}
+	white, whiteBits, ok := drawIconDIB(hicon, size, 0xFF)
+	if !ok {
+		black.Delete()
+		return 0, false
+	}
+	defer white.Delete()
+	for i := 0; i < len(blackBits); i += 4 {
+		blackBits[i+3] = 0xFF - (whiteBits[i+1] - blackBits[i+1])
+	}
+	return black, true
+}
+
+func drawIconDIB(hicon winmenu.HIcon, size int, background byte) (hbm winmenu.HBitmap, bits []byte, ok bool) {
+	bmi := bitmapInfoHeader{
+		width:    int32(size),
+		height:   -int32(size),
+		planes:   1,
+		bitCount: 32,
+	}
+	bmi.size = uint32(unsafe.Sizeof(bmi))
+	var p unsafe.Pointer
+	ret, _, _ := procCreateDIBSection.Call(0, uintptr(unsafe.Pointer(&bmi)), dibRGBColors,
+		uintptr(unsafe.Pointer(&p)), 0, 0)
+	if ret == 0 {
+		return 0, nil, false
+	}
+	hbm = winmenu.HBitmap(ret)
+	bits = unsafe.Slice((*byte)(p), size*size*4)
+	for i := range bits {
+		bits[i] = background
+	}
+	hdc, _, _ := procCreateCompatibleDC.Call(0)
+	if hdc == 0 {
+		hbm.Delete()
+		return 0, nil, false
+	}
+	defer procDeleteDC.Call(hdc)
+	old, _, _ := procSelectObject.Call(hdc, uintptr(hbm))
+	ret, _, _ = procDrawIconEx.Call(hdc, 0, 0, uintptr(hicon), uintptr(size), uintptr(size), 0, 0, diNormal)
+	procSelectObject.Call(hdc, old)
+	procGdiFlush.Call()
+	if ret == 0 {
+		hbm.Delete()
+		return 0, nil, false
+	}
+	return hbm, bits, true
+}
+
+// LoadItemBitmapAsync shows placeholder next to the text of the item of hmenu
+// with the given command ID, then runs load on a new goroutine and swaps the
+// loaded image in once it is ready. If done is not nil, it is called from that
+// goroutine with the new bitmap, which the caller owns, or with the error that
+// prevented the swap.
+func LoadItemBitmapAsync(hmenu winmenu.HMenu, id uint32, placeholder winmenu.HBitmap,
+	load func() (image.Image, error), done func(winmenu.HBitmap, error)) (ok bool) {
+	if !setItemBitmap(hmenu, id, placeholder) {
+		return false
+	}
+	go func() {
+		hbm, err := func() (winmenu.HBitmap, error) {
+			img, err := load()
+			if err != nil {
+				return 0, err
+			}
+			hbm, err := BitmapFromImage(img)
+			if err != nil {
+				return 0, err
+			}
+			// The item may have been deleted while loading. Probe for it
+			// without going through the strict mode, which would panic on
+			// this goroutine, out of the caller's reach.
+			if !itemExists(hmenu, id) || !setItemBitmap(hmenu, id, hbm) {
+				hbm.Delete()
+				return 0, errors.New("winmenu: menu item no longer exists")
+			}
+			return hbm, nil
+		}()
+		if done != nil {
+			done(hbm, err)
+		}
+	}()
+	return true
+}
+
+// itemExists reports whether hmenu has an item with the given command ID.
+func itemExists(hmenu winmenu.HMenu, id uint32) bool {
+	ret, _, _ := procGetMenuState.Call(uintptr(hmenu), uintptr(id), uintptr(winmenu.MF_BYCOMMAND))
+	return uint32(ret) != 0xFFFFFFFF
+}
+
+func setItemBitmap(hmenu winmenu.HMenu, id uint32, hbm winmenu.HBitmap) (ok bool) {
+	mii := winmenu.NewMenuItemInfo()
+	mii.SetItemBitmap(hbm)
+	return hmenu.SetMenuItemInfo(id, false, mii)
+}
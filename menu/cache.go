@@ -0,0 +1,92 @@
+package menu
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"sync"
+
+	"github.com/kroppt/winmenu"
+)
+
+type cacheEntry struct {
+	hbm  winmenu.HBitmap
+	refs int
+}
+
+// BitmapCache shares one bitmap among all items and menus that show the same
+// image, instead of creating a GDI object per item. Bitmaps are looked up by a
+// hash of their pixels and reference counted. It is safe to use from multiple
+// goroutines.
+type BitmapCache struct {
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*cacheEntry
+	keys    map[winmenu.HBitmap][sha256.Size]byte
+}
+
+// NewBitmapCache returns a pointer to a new, empty BitmapCache.
+func NewBitmapCache() *BitmapCache {
+	return &BitmapCache{
+		entries: map[[sha256.Size]byte]*cacheEntry{},
+		keys:    map[winmenu.HBitmap][sha256.Size]byte{},
+	}
+}
+
+// Bitmap returns a bitmap of img as made by BitmapFromImage, reusing the
+// cached one if an image with the same pixels was added before. Every call must
+// be matched by a call to Release once no item uses the bitmap anymore; the
+// bitmap must not be deleted directly.
+func (c *BitmapCache) Bitmap(img image.Image) (winmenu.HBitmap, error) {
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	h := sha256.New()
+	var size [8]byte
+	binary.LittleEndian.PutUint32(size[0:], uint32(b.Dx()))
+	binary.LittleEndian.PutUint32(size[4:], uint32(b.Dy()))
+	h.Write(size[:])
+	h.Write(rgba.Pix)
+	var key [sha256.Size]byte
+	copy(key[:], h.Sum(nil))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		e.refs++
+		return e.hbm, nil
+	}
+	hbm, err := BitmapFromImage(rgba)
+	if err != nil {
+		return 0, err
+	}
+	c.entries[key] = &cacheEntry{hbm: hbm, refs: 1}
+	c.keys[hbm] = key
+	return hbm, nil
+}
+
+// Release drops a reference to a bitmap returned by Bitmap, deleting the bitmap
+// when no references are left. It returns false if hbm is not in the cache.
+func (c *BitmapCache) Release(hbm winmenu.HBitmap) (ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key, ok := c.keys[hbm]
+	if !ok {
+		return false
+	}
+	e := c.entries[key]
+	e.refs--
+	if e.refs > 0 {
+		return true
+	}
+	delete(c.entries, key)
+	delete(c.keys, hbm)
+	return hbm.Delete()
+}
+
+// Len returns the number of distinct bitmaps in the cache.
+func (c *BitmapCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
@@ -0,0 +1,218 @@
+package menu
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/kroppt/winmenu"
+	"github.com/kroppt/winmenu/internal/win"
+)
+
+var (
+	procGlobalAlloc                   = win.Kernel32.NewProc("GlobalAlloc")
+	procGlobalFree                    = win.Kernel32.NewProc("GlobalFree")
+	procGlobalLock                    = win.Kernel32.NewProc("GlobalLock")
+	procGlobalUnlock                  = win.Kernel32.NewProc("GlobalUnlock")
+	procGlobalSize                    = win.Kernel32.NewProc("GlobalSize")
+	procRtlMoveMemory                 = win.Kernel32.NewProc("RtlMoveMemory")
+	procAddClipboardFormatListener    = win.User32.NewProc("AddClipboardFormatListener")
+	procRemoveClipboardFormatListener = win.User32.NewProc("RemoveClipboardFormatListener")
+	procOpenClipboard                 = win.User32.NewProc("OpenClipboard")
+	procCloseClipboard                = win.User32.NewProc("CloseClipboard")
+	procEmptyClipboard                = win.User32.NewProc("EmptyClipboard")
+	procGetClipboardData              = win.User32.NewProc("GetClipboardData")
+	procSetClipboardData              = win.User32.NewProc("SetClipboardData")
+	procGetMenuItemCount              = win.User32.NewProc("GetMenuItemCount")
+)
+
+const (
+	cfUnicodeText = 13
+	gmemMoveable  = 0x0002
+)
+
+// ClipboardHistory is a popup menu listing recently copied text, newest
+// first. Choosing an entry copies it to the clipboard again.
+type ClipboardHistory struct {
+	// The popup menu, ready to be attached with SetSubMenu.
+	Menu winmenu.HMenu
+	// The maximum number of entries kept.
+	Max int
+	// The maximum length of an item label in characters. Longer entries are
+	// truncated with an ellipsis.
+	MaxLabel int
+	// Whether items are numbered with Renumber, so that the most recent entries
+	// can be chosen by typing a digit.
+	Numbered bool
+	entries  []string
+	firstID  uint32
+	hwnd     winmenu.HWnd
+}
+
+// NewClipboardHistory registers hwnd as a clipboard format listener and
+// creates an empty ClipboardHistory whose items use the command IDs firstID
+// through firstID+max-1. The window procedure of hwnd must call Update on
+// WM_CLIPBOARDUPDATE and Select on WM_COMMAND.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-addclipboardformatlistener)
+func NewClipboardHistory(hwnd winmenu.HWnd, firstID uint32, max int) (ch *ClipboardHistory, ok bool) {
+	hmenu, ok := winmenu.CreatePopupMenu()
+	if !ok {
+		return nil, false
+	}
+	ret, _, _ := procAddClipboardFormatListener.Call(uintptr(hwnd))
+	if ret == 0 {
+		hmenu.Destroy()
+		return nil, false
+	}
+	ch = &ClipboardHistory{Menu: hmenu, Max: max, MaxLabel: 40, firstID: firstID, hwnd: hwnd}
+	if !ch.rebuild() {
+		ch.Close()
+		Forget(hmenu)
+		hmenu.Destroy()
+		return nil, false
+	}
+	return ch, true
+}
+
+// Close stops listening for clipboard changes.
+func (ch *ClipboardHistory) Close() (ok bool) {
+	ret, _, _ := procRemoveClipboardFormatListener.Call(uintptr(ch.hwnd))
+	return ret != 0
+}
+
+// Entries returns the remembered clipboard text, newest first.
+func (ch *ClipboardHistory) Entries() []string {
+	return append([]string(nil), ch.entries...)
+}
+
+// Update reads the clipboard and, if it holds text, moves the text to the top
+// of the history.
+func (ch *ClipboardHistory) Update() (ok bool) {
+	text, ok := ch.readClipboard()
+	if !ok || text == "" {
+		return ok
+	}
+	entries := []string{text}
+	for _, e := range ch.entries {
+		if e != text && len(entries) < ch.Max {
+			entries = append(entries, e)
+		}
+	}
+	ch.entries = entries
+	return ch.rebuild()
+}
+
+// Select handles a WM_COMMAND ID, copying the chosen entry to the clipboard if
+// the ID belongs to one of the menu's items. It reports whether the ID was
+// handled.
+func (ch *ClipboardHistory) Select(id uint32) bool {
+	if id < ch.firstID || id-ch.firstID >= uint32(len(ch.entries)) {
+		return false
+	}
+	ch.writeClipboard(ch.entries[id-ch.firstID])
+	return true
+}
+
+func (ch *ClipboardHistory) rebuild() (ok bool) {
+	if !clearMenu(ch.Menu) {
+		return false
+	}
+	if len(ch.entries) == 0 {
+		mii := winmenu.NewMenuItemInfo()
+		mii.SetAsString("(empty)")
+		mii.SetState(winmenu.MFS_DISABLED)
+		return ch.Menu.InsertMenuItem(0, true, mii)
+	}
+	for i, e := range ch.entries {
+		mii := winmenu.NewMenuItemInfo()
+		mii.SetAsString(itemLabel(e, ch.MaxLabel))
+		mii.SetID(ch.firstID + uint32(i))
+		if !ch.Menu.InsertMenuItem(uint32(i), true, mii) {
+			return false
+		}
+	}
+	if ch.Numbered {
+		return Renumber(ch.Menu)
+	}
+	return true
+}
+
+// clearMenu deletes all items of hmenu.
+func clearMenu(hmenu winmenu.HMenu) (ok bool) {
+	for {
+		n, _, _ := procGetMenuItemCount.Call(uintptr(hmenu))
+		if int32(n) <= 0 {
+			return int32(n) == 0
+		}
+		if !hmenu.DeleteMenu(0, true) {
+			return false
+		}
+	}
+}
+
+// itemLabel turns text into a single-line item label of at most max
+// characters.
+func itemLabel(text string, max int) string {
+	label := strings.Join(strings.Fields(text), " ")
+	if r := []rune(label); max > 0 && len(r) > max {
+		label = string(r[:max-1]) + "…"
+	}
+	return strings.Replace(label, "&", "&&", -1)
+}
+
+func (ch *ClipboardHistory) readClipboard() (text string, ok bool) {
+	ret, _, _ := procOpenClipboard.Call(uintptr(ch.hwnd))
+	if ret == 0 {
+		return "", false
+	}
+	defer procCloseClipboard.Call()
+	h, _, _ := procGetClipboardData.Call(cfUnicodeText)
+	if h == 0 {
+		// No text on the clipboard.
+		return "", true
+	}
+	size, _, _ := procGlobalSize.Call(h)
+	p, _, _ := procGlobalLock.Call(h)
+	if p == 0 {
+		return "", false
+	}
+	defer procGlobalUnlock.Call(h)
+	buf := make([]uint16, size/2+1)
+	if size > 0 {
+		procRtlMoveMemory.Call(uintptr(unsafe.Pointer(&buf[0])), p, size)
+	}
+	return syscall.UTF16ToString(buf), true
+}
+
+func (ch *ClipboardHistory) writeClipboard(text string) (ok bool) {
+	buf, err := syscall.UTF16FromString(text)
+	if err != nil {
+		return false
+	}
+	size := uintptr(len(buf) * 2)
+	h, _, _ := procGlobalAlloc.Call(gmemMoveable, size)
+	if h == 0 {
+		return false
+	}
+	p, _, _ := procGlobalLock.Call(h)
+	if p == 0 {
+		procGlobalFree.Call(h)
+		return false
+	}
+	procRtlMoveMemory.Call(p, uintptr(unsafe.Pointer(&buf[0])), size)
+	procGlobalUnlock.Call(h)
+	ret, _, _ := procOpenClipboard.Call(uintptr(ch.hwnd))
+	if ret == 0 {
+		procGlobalFree.Call(h)
+		return false
+	}
+	defer procCloseClipboard.Call()
+	procEmptyClipboard.Call()
+	ret, _, _ = procSetClipboardData.Call(cfUnicodeText, h)
+	if ret == 0 {
+		// Ownership only passes to the system on success.
+		procGlobalFree.Call(h)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package menu
+
+import (
+	"sync"
+
+	"github.com/kroppt/winmenu"
+)
+
+// DestroyQueue holds menus and bitmaps that have been replaced while a menu
+// may still be showing them, and destroys them once the menu session ends.
+// Destroying a menu or deleting a bitmap that Windows is still displaying
+// can crash the menu loop. It is safe to use from multiple goroutines.
+type DestroyQueue struct {
+	mu      sync.Mutex
+	menus   []winmenu.HMenu
+	bitmaps []winmenu.HBitmap
+}
+
+// Add queues hmenu, which may be zero, and bitmaps for destruction. The menu
+// must already be detached from its parent, for example with RemoveMenu, so it
+// is not destroyed twice.
+func (q *DestroyQueue) Add(hmenu winmenu.HMenu, bitmaps ...winmenu.HBitmap) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if hmenu != 0 {
+		q.menus = append(q.menus, hmenu)
+	}
+	q.bitmaps = append(q.bitmaps, bitmaps...)
+}
+
+// Flush destroys the queued menus, forgetting their numbering as Forget does,
+// then deletes the queued bitmaps. Call it from the window procedure of the
+// owner window on WM_EXITMENULOOP. It returns false if any of them could not
+// be released.
+func (q *DestroyQueue) Flush() (ok bool) {
+	q.mu.Lock()
+	menus, bitmaps := q.menus, q.bitmaps
+	q.menus, q.bitmaps = nil, nil
+	q.mu.Unlock()
+	ok = true
+	for _, hmenu := range menus {
+		Forget(hmenu)
+		ok = hmenu.Destroy() && ok
+	}
+	for _, hbm := range bitmaps {
+		ok = hbm.Delete() && ok
+	}
+	return ok
+}
@@ -0,0 +1,7 @@
+// Package menu builds on the raw wrappers of package winmenu with menu
+// builders, image conversion helpers, and ready-made menu components.
+//
+// Components create a popup menu whose items use a caller-chosen range of
+// command IDs. The application's window procedure passes WM_COMMAND IDs to the
+// component's Select method, which reports whether the ID was its own.
+package menu
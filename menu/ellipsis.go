@@ -0,0 +1,140 @@
+package menu
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/kroppt/winmenu/internal/win"
+)
+
+var (
+	procSystemParametersInfo = win.User32.NewProc("SystemParametersInfoW")
+	procGetDialogBaseUnits   = win.User32.NewProc("GetDialogBaseUnits")
+	procCreateFontIndirect   = win.Gdi32.NewProc("CreateFontIndirectW")
+	procGetTextExtentPoint32 = win.Gdi32.NewProc("GetTextExtentPoint32W")
+)
+
+const spiGetNonClientMetrics = 0x0029
+
+type logFont struct {
+	height         int32
+	width          int32
+	escapement     int32
+	orientation    int32
+	weight         int32
+	italic         byte
+	underline      byte
+	strikeOut      byte
+	charSet        byte
+	outPrecision   byte
+	clipPrecision  byte
+	quality        byte
+	pitchAndFamily byte
+	faceName       [32]uint16
+}
+
+type nonClientMetrics struct {
+	cbSize            uint32
+	borderWidth       int32
+	scrollWidth       int32
+	scrollHeight      int32
+	captionWidth      int32
+	captionHeight     int32
+	captionFont       logFont
+	smCaptionWidth    int32
+	smCaptionHeight   int32
+	smCaptionFont     logFont
+	menuWidth         int32
+	menuHeight        int32
+	menuFont          logFont
+	statusFont        logFont
+	messageFont       logFont
+	paddedBorderWidth int32
+}
+
+// EllipsisMode selects where Ellipsize shortens text.
+type EllipsisMode int
+
+const (
+	// EllipsisEnd keeps the start of the text, as in "Quarterly rep…".
+	EllipsisEnd EllipsisMode = iota
+	// EllipsisMiddle keeps both ends of the text, which suits paths and URLs,
+	// as in "C:\Users\…\report.docx".
+	EllipsisMiddle
+)
+
+// Ellipsize shortens text with an ellipsis until it fits in maxWidth pixels
+// when drawn in the system menu font. Text that already fits is returned as
+// is. Ellipsize measures the text literally, so escape ampersands in the
+// result, not before, when using it as an item label.
+func Ellipsize(text string, maxWidth int, mode EllipsisMode) (label string, ok bool) {
+	ncm := nonClientMetrics{}
+	ncm.cbSize = uint32(unsafe.Sizeof(ncm))
+	ret, _, _ := procSystemParametersInfo.Call(spiGetNonClientMetrics, uintptr(ncm.cbSize), uintptr(unsafe.Pointer(&ncm)), 0)
+	if ret == 0 {
+		return "", false
+	}
+	hdc, _, _ := procCreateCompatibleDC.Call(0)
+	if hdc == 0 {
+		return "", false
+	}
+	defer procDeleteDC.Call(hdc)
+	font, _, _ := procCreateFontIndirect.Call(uintptr(unsafe.Pointer(&ncm.menuFont)))
+	if font == 0 {
+		return "", false
+	}
+	defer procDeleteObject.Call(font)
+	old, _, _ := procSelectObject.Call(hdc, font)
+	defer procSelectObject.Call(hdc, old)
+	width := func(s string) (int, bool) {
+		if s == "" {
+			return 0, true
+		}
+		u, err := syscall.UTF16FromString(s)
+		if err != nil {
+			return 0, false
+		}
+		var size [2]int32
+		ret, _, _ := procGetTextExtentPoint32.Call(hdc, uintptr(unsafe.Pointer(&u[0])), uintptr(len(u)-1), uintptr(unsafe.Pointer(&size)))
+		return int(size[0]), ret != 0
+	}
+	w, ok := width(text)
+	if !ok {
+		return "", false
+	}
+	if w <= maxWidth {
+		return text, true
+	}
+	runes := []rune(text)
+	shorten := func(n int) string {
+		if mode == EllipsisMiddle {
+			head := (n + 1) / 2
+			return string(runes[:head]) + "…" + string(runes[len(runes)-(n-head):])
+		}
+		return string(runes[:n]) + "…"
+	}
+	// Find the most characters that fit.
+	lo, hi := 0, len(runes)-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		w, ok := width(shorten(mid))
+		if !ok {
+			return "", false
+		}
+		if w <= maxWidth {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return shorten(lo), true
+}
+
+// DialogUnitsToPixels converts a horizontal distance in dialog units to pixels,
+// based on the system dialog base units, for use as the width of Ellipsize.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-getdialogbaseunits)
+func DialogUnitsToPixels(dlu int) int {
+	ret, _, _ := procGetDialogBaseUnits.Call()
+	baseX := int(ret & 0xFFFF)
+	return (dlu*baseX + 2) / 4
+}
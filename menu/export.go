@@ -0,0 +1,157 @@
+package menu
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/kroppt/winmenu"
+)
+
+// exportItem is a menu item as written by WriteMarkdown and WriteHTML.
+type exportItem struct {
+	text     string
+	shortcut string
+	disabled bool
+	children []exportItem
+}
+
+// readItems reads the string items of hmenu and its submenus, skipping
+// separators and bitmap items.
+func readItems(hmenu winmenu.HMenu) ([]exportItem, error) {
+	n, _, _ := procGetMenuItemCount.Call(uintptr(hmenu))
+	if int32(n) < 0 {
+		return nil, errors.New("winmenu: invalid menu")
+	}
+	var items []exportItem
+	for pos := uint32(0); pos < uint32(n); pos++ {
+		mii := winmenu.NewMenuItemInfo()
+		mii.SetMask(winmenu.MIIM_STRING | winmenu.MIIM_FTYPE | winmenu.MIIM_STATE | winmenu.MIIM_SUBMENU)
+		if !hmenu.GetMenuItemInfo(pos, true, mii) {
+			return nil, fmt.Errorf("winmenu: cannot read menu item %d", pos)
+		}
+		label := mii.Text()
+		if label == "" {
+			continue
+		}
+		item := exportItem{
+			text:     plainText(label),
+			disabled: mii.State()&winmenu.MFS_DISABLED != 0,
+		}
+		if i := strings.IndexByte(label, '\t'); i >= 0 {
+			item.shortcut = label[i+1:]
+		}
+		if sub := mii.SubMenu(); sub != 0 {
+			children, err := readItems(sub)
+			if err != nil {
+				return nil, err
+			}
+			item.children = children
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// WriteMarkdown writes an outline of hmenu and its submenus to w as a nested
+// Markdown list, for use in user documentation. Shortcuts are shown as code
+// and disabled items are marked. Separators and bitmap items are left out.
+// Characters that Markdown would read as formatting are escaped.
+func WriteMarkdown(w io.Writer, hmenu winmenu.HMenu) error {
+	items, err := readItems(hmenu)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	var write func(items []exportItem, depth int)
+	write = func(items []exportItem, depth int) {
+		for _, item := range items {
+			b.WriteString(strings.Repeat("  ", depth))
+			b.WriteString("- ")
+			b.WriteString(markdownText(item.text))
+			if item.shortcut != "" {
+				b.WriteString(" " + markdownCode(item.shortcut))
+			}
+			if item.disabled {
+				b.WriteString(" (disabled)")
+			}
+			b.WriteByte('\n')
+			write(item.children, depth+1)
+		}
+	}
+	write(items, 0)
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// markdownEscaper backslash-escapes the characters that Markdown could read as
+// formatting.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "{", `\{`, "}", `\}`,
+	"[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`, "#", `\#`, "+", `\+`,
+	"-", `\-`, ".", `\.`, "!", `\!`, "|", `\|`, "<", `\<`, ">", `\>`,
+	"~", `\~`, "&", `\&`,
+)
+
+// markdownText returns s escaped for use as Markdown text.
+func markdownText(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// markdownCode returns s as a Markdown code span. Backslash escapes do not
+// work in code spans, so the span is delimited by more backticks than s
+// contains in a row, and padded with spaces if s starts or ends with one.
+func markdownCode(s string) string {
+	run, longest := 0, 0
+	for _, r := range s {
+		if r != '`' {
+			run = 0
+			continue
+		}
+		run++
+		if run > longest {
+			longest = run
+		}
+	}
+	fence := strings.Repeat("`", longest+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+	return fence + s + fence
+}
+
+// WriteHTML is like WriteMarkdown, but writes nested HTML lists, with
+// shortcuts in kbd elements and disabled items in the "disabled" class.
+func WriteHTML(w io.Writer, hmenu winmenu.HMenu) error {
+	items, err := readItems(hmenu)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	var write func(items []exportItem)
+	write = func(items []exportItem) {
+		b.WriteString("<ul>\n")
+		for _, item := range items {
+			if item.disabled {
+				b.WriteString(`<li class="disabled">`)
+			} else {
+				b.WriteString("<li>")
+			}
+			b.WriteString(html.EscapeString(item.text))
+			if item.shortcut != "" {
+				b.WriteString(" <kbd>" + html.EscapeString(item.shortcut) + "</kbd>")
+			}
+			if len(item.children) > 0 {
+				b.WriteByte('\n')
+				write(item.children)
+			}
+			b.WriteString("</li>\n")
+		}
+		b.WriteString("</ul>\n")
+	}
+	write(items)
+	_, err = io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,40 @@
+package menu
+
+import "testing"
+
+func TestMarkdownText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"Open", "Open"},
+		{"Save *all*", `Save \*all\*`},
+		{"my_file.txt", `my\_file\.txt`},
+		{"[Link](x)", `\[Link\]\(x\)`},
+		{"# Heading", `\# Heading`},
+		{"`code`", "\\`code\\`"},
+		{`C:\Temp`, `C:\\Temp`},
+		{"<b>&amp;", `\<b\>\&amp;`},
+	}
+	for _, tt := range tests {
+		if got := markdownText(tt.in); got != tt.want {
+			t.Errorf("markdownText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownCode(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Ctrl+S", "`Ctrl+S`"},
+		{"Ctrl+*", "`Ctrl+*`"},
+		{"Ctrl+`", "`` Ctrl+` ``"},
+		{"a``b", "```a``b```"},
+	}
+	for _, tt := range tests {
+		if got := markdownCode(tt.in); got != tt.want {
+			t.Errorf("markdownCode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
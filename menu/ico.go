@@ -0,0 +1,180 @@
+package menu
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+var (
+	errBadICO = errors.New("winmenu: invalid ICO data")
+	pngMagic  = []byte("\x89PNG\r\n\x1a\n")
+)
+
+const icoHeaderSize = 6
+
+// icoEntry is an ICONDIRENTRY from an ICO file.
+type icoEntry struct {
+	width, height int
+	bitCount      int
+	size, offset  uint32
+}
+
+// decodeICO decodes the image in an ICO file that best fits size pixels: the
+// smallest image at least that large, or the largest image otherwise.
+func decodeICO(data []byte, size int) (image.Image, error) {
+	if len(data) < icoHeaderSize || binary.LittleEndian.Uint16(data[0:]) != 0 ||
+		binary.LittleEndian.Uint16(data[2:]) != 1 {
+		return nil, errBadICO
+	}
+	count := int(binary.LittleEndian.Uint16(data[4:]))
+	if count == 0 || len(data) < icoHeaderSize+count*16 {
+		return nil, errBadICO
+	}
+	var best *icoEntry
+	for i := 0; i < count; i++ {
+		d := data[icoHeaderSize+i*16:]
+		e := icoEntry{
+			width:    int(d[0]),
+			height:   int(d[1]),
+			bitCount: int(binary.LittleEndian.Uint16(d[6:])),
+			size:     binary.LittleEndian.Uint32(d[8:]),
+			offset:   binary.LittleEndian.Uint32(d[12:]),
+		}
+		// A stored dimension of zero means 256.
+		if e.width == 0 {
+			e.width = 256
+		}
+		if e.height == 0 {
+			e.height = 256
+		}
+		if uint64(e.offset)+uint64(e.size) > uint64(len(data)) {
+			continue
+		}
+		if best == nil || betterICOEntry(e, *best, size) {
+			best = &e
+		}
+	}
+	if best == nil {
+		return nil, errBadICO
+	}
+	img := data[best.offset : best.offset+best.size]
+	if bytes.HasPrefix(img, pngMagic) {
+		return png.Decode(bytes.NewReader(img))
+	}
+	return decodeICODIB(img)
+}
+
+func betterICOEntry(e, best icoEntry, size int) bool {
+	if e.width != best.width {
+		eFits, bestFits := e.width >= size, best.width >= size
+		if eFits != bestFits {
+			return eFits
+		}
+		if eFits {
+			return e.width < best.width
+		}
+		return e.width > best.width
+	}
+	return e.bitCount > best.bitCount
+}
+
+// decodeICODIB decodes a BITMAPINFOHEADER-based icon image: a bottom-up color
+// bitmap of double height followed by a 1 bpp transparency mask.
+func decodeICODIB(data []byte) (image.Image, error) {
+	if len(data) < 40 {
+		return nil, errBadICO
+	}
+	hdrSize := int(binary.LittleEndian.Uint32(data[0:]))
+	w := int(int32(binary.LittleEndian.Uint32(data[4:])))
+	h := int(int32(binary.LittleEndian.Uint32(data[8:]))) / 2
+	bpp := int(binary.LittleEndian.Uint16(data[14:]))
+	compression := binary.LittleEndian.Uint32(data[16:])
+	clrUsed := int(binary.LittleEndian.Uint32(data[32:]))
+	if hdrSize < 40 || hdrSize > len(data) || w <= 0 || h <= 0 || w > 1024 || h > 1024 || compression != 0 {
+		return nil, errBadICO
+	}
+	switch bpp {
+	case 1, 4, 8, 24, 32:
+	default:
+		return nil, errBadICO
+	}
+	var palette []color.NRGBA
+	if bpp <= 8 {
+		if clrUsed == 0 || clrUsed > 1<<uint(bpp) {
+			clrUsed = 1 << uint(bpp)
+		}
+		if len(data) < hdrSize+clrUsed*4 {
+			return nil, errBadICO
+		}
+		palette = make([]color.NRGBA, clrUsed)
+		for i := range palette {
+			p := data[hdrSize+i*4:]
+			palette[i] = color.NRGBA{R: p[2], G: p[1], B: p[0], A: 0xFF}
+		}
+	}
+	xorStride := (w*bpp + 31) / 32 * 4
+	andStride := (w + 31) / 32 * 4
+	xorOff := hdrSize + len(palette)*4
+	andOff := xorOff + xorStride*h
+	if len(data) < andOff {
+		return nil, errBadICO
+	}
+	hasMask := len(data) >= andOff+andStride*h
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	hasAlpha := false
+	for y := 0; y < h; y++ {
+		row, ok := dibRow(data, xorOff, xorStride, h-1-y)
+		if !ok {
+			return nil, errBadICO
+		}
+		for x := 0; x < w; x++ {
+			var c color.NRGBA
+			switch bpp {
+			case 32:
+				c = color.NRGBA{R: row[x*4+2], G: row[x*4+1], B: row[x*4], A: row[x*4+3]}
+				hasAlpha = hasAlpha || c.A != 0
+			case 24:
+				c = color.NRGBA{R: row[x*3+2], G: row[x*3+1], B: row[x*3], A: 0xFF}
+			default:
+				bit := x * bpp
+				idx := int(row[bit/8]>>uint(8-bpp-bit%8)) & (1<<uint(bpp) - 1)
+				if idx < len(palette) {
+					c = palette[idx]
+				}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	// 32 bpp images carry their own alpha; everything else relies on the mask.
+	if hasMask && !hasAlpha {
+		for y := 0; y < h; y++ {
+			row, ok := dibRow(data, andOff, andStride, h-1-y)
+			if !ok {
+				return nil, errBadICO
+			}
+			for x := 0; x < w; x++ {
+				c := img.NRGBAAt(x, y)
+				c.A = 0xFF
+				if row[x/8]&(0x80>>uint(x%8)) != 0 {
+					c.A = 0
+				}
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+	return img, nil
+}
+
+// dibRow returns row i of the bitmap rows of length stride starting at off in
+// data, or ok false if the row does not fit.
+func dibRow(data []byte, off, stride, i int) (row []byte, ok bool) {
+	start := off + i*stride
+	if start < 0 || stride <= 0 || start+stride > len(data) {
+		return nil, false
+	}
+	return data[start : start+stride], true
+}
@@ -0,0 +1,76 @@
+package menu
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// dib returns a BITMAPINFOHEADER icon image of the given size and bit count,
+// followed by extra bytes of pixel data.
+func dib(w, h int32, bpp uint16, extra int) []byte {
+	data := make([]byte, 40+extra)
+	binary.LittleEndian.PutUint32(data[0:], 40)
+	binary.LittleEndian.PutUint32(data[4:], uint32(w))
+	binary.LittleEndian.PutUint32(data[8:], uint32(h*2))
+	binary.LittleEndian.PutUint16(data[12:], 1)
+	binary.LittleEndian.PutUint16(data[14:], bpp)
+	return data
+}
+
+// ico wraps image data in an ICO file with a single entry.
+func ico(img []byte) []byte {
+	data := make([]byte, icoHeaderSize+16, icoHeaderSize+16+len(img))
+	binary.LittleEndian.PutUint16(data[2:], 1)
+	binary.LittleEndian.PutUint16(data[4:], 1)
+	binary.LittleEndian.PutUint32(data[icoHeaderSize+8:], uint32(len(img)))
+	binary.LittleEndian.PutUint32(data[icoHeaderSize+12:], icoHeaderSize+16)
+	return append(data, img...)
+}
+
+func TestDecodeICODIB(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		ok   bool
+	}{
+		{"empty", nil, false},
+		{"short header", make([]byte, 39), false},
+		{"bpp 0", dib(1, 1, 0, 4), false},
+		{"bpp 2", dib(1, 1, 2, 64), false},
+		{"bpp 16", dib(1, 1, 16, 64), false},
+		{"huge width", dib(1<<30, 1, 32, 64), false},
+		{"huge height", dib(1, 1<<29, 32, 64), false},
+		{"negative width", dib(-1, 1, 32, 64), false},
+		{"truncated 32 bpp", dib(16, 16, 32, 16*16*4-1), false},
+		{"truncated palette", dib(1, 1, 8, 255*4), false},
+		{"truncated 1 bpp rows", dib(32, 32, 1, 2*4+31*4), false},
+		{"32 bpp without mask", dib(2, 2, 32, 2*2*4), true},
+		{"32 bpp with mask", dib(2, 2, 32, 2*2*4+2*4), true},
+		{"24 bpp", dib(2, 2, 24, 2*8+2*4), true},
+		{"8 bpp", dib(2, 2, 8, 256*4+2*4+2*4), true},
+		{"4 bpp", dib(2, 2, 4, 16*4+2*4+2*4), true},
+		{"1 bpp", dib(2, 2, 1, 2*4+2*4+2*4), true},
+	}
+	for _, tt := range tests {
+		img, err := decodeICODIB(tt.data)
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("%s: got error %v, want ok %t", tt.name, err, tt.ok)
+			continue
+		}
+		if err == nil && img.Bounds().Dx() == 0 {
+			t.Errorf("%s: got an empty image", tt.name)
+		}
+		if _, err := decodeICO(ico(tt.data), 16); (err == nil) != tt.ok {
+			t.Errorf("%s: decodeICO got error %v, want ok %t", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestDecodeICOTruncated(t *testing.T) {
+	data := ico(dib(2, 2, 32, 2*2*4))
+	for n := 0; n < len(data); n++ {
+		if _, err := decodeICO(data[:n], 16); err == nil {
+			t.Errorf("decodeICO of %d of %d bytes succeeded", n, len(data))
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package menu
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/kroppt/winmenu"
+	"github.com/kroppt/winmenu/internal/win"
+)
+
+var (
+	procGetKeyboardLayoutList  = win.User32.NewProc("GetKeyboardLayoutList")
+	procGetKeyboardLayout      = win.User32.NewProc("GetKeyboardLayout")
+	procActivateKeyboardLayout = win.User32.NewProc("ActivateKeyboardLayout")
+	procGetLocaleInfo          = win.Kernel32.NewProc("GetLocaleInfoW")
+)
+
+const (
+	localeSLocalizedDisplayName = 0x00000002
+	klfSetForProcess            = 0x00000100
+)
+
+// KeyboardLayout describes an installed input language.
+type KeyboardLayout struct {
+	// The HKL of the layout.
+	Handle uintptr
+	// The localized display name of the input language.
+	Name string
+}
+
+// KeyboardLayouts returns the installed input languages and the index of the
+// one active for the calling thread, or -1 if it is not in the list.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-getkeyboardlayoutlist)
+func KeyboardLayouts() (layouts []KeyboardLayout, active int, ok bool) {
+	n, _, _ := procGetKeyboardLayoutList.Call(0, 0)
+	if n == 0 {
+		return nil, -1, false
+	}
+	hkls := make([]uintptr, n)
+	n, _, _ = procGetKeyboardLayoutList.Call(n, uintptr(unsafe.Pointer(&hkls[0])))
+	if n == 0 {
+		return nil, -1, false
+	}
+	current, _, _ := procGetKeyboardLayout.Call(0)
+	active = -1
+	for i, hkl := range hkls[:n] {
+		if hkl == current {
+			active = i
+		}
+		layouts = append(layouts, KeyboardLayout{Handle: hkl, Name: layoutName(hkl)})
+	}
+	return layouts, active, true
+}
+
+func layoutName(hkl uintptr) string {
+	langID := hkl & 0xFFFF
+	buf := make([]uint16, 128)
+	ret, _, _ := procGetLocaleInfo.Call(langID, localeSLocalizedDisplayName,
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	if ret == 0 {
+		return ""
+	}
+	return syscall.UTF16ToString(buf)
+}
+
+// Activate makes the layout the active input language of the calling process.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-activatekeyboardlayout)
+func (kl KeyboardLayout) Activate() (ok bool) {
+	ret, _, _ := procActivateKeyboardLayout.Call(kl.Handle, klfSetForProcess)
+	return ret != 0
+}
+
+// KeyboardLayoutMenu is a popup menu listing the installed input languages,
+// with the active one radio-checked. Choosing an entry activates the layout.
+type KeyboardLayoutMenu struct {
+	// The popup menu, ready to be attached with SetSubMenu.
+	Menu winmenu.HMenu
+	// The listed layouts, in item order.
+	Layouts []KeyboardLayout
+	firstID uint32
+}
+
+// NewKeyboardLayoutMenu creates a KeyboardLayoutMenu whose items use the
+// command IDs firstID through firstID+len(Layouts)-1.
+func NewKeyboardLayoutMenu(firstID uint32) (klm *KeyboardLayoutMenu, ok bool) {
+	layouts, active, ok := KeyboardLayouts()
+	if !ok {
+		return nil, false
+	}
+	hmenu, ok := winmenu.CreatePopupMenu()
+	if !ok {
+		return nil, false
+	}
+	for i, kl := range layouts {
+		mii := winmenu.NewMenuItemInfo()
+		mii.SetAsString(itemLabel(kl.Name, 0))
+		mii.SetID(firstID + uint32(i))
+		mii.SetRadioCheck()
+		if i == active {
+			mii.SetState(winmenu.MFS_CHECKED)
+		}
+		if !hmenu.InsertMenuItem(uint32(i), true, mii) {
+			hmenu.Destroy()
+			return nil, false
+		}
+	}
+	return &KeyboardLayoutMenu{Menu: hmenu, Layouts: layouts, firstID: firstID}, true
+}
+
+// Select handles a WM_COMMAND ID, activating the chosen layout if the ID
+// belongs to one of the menu's items. It reports whether the ID was handled.
+func (klm *KeyboardLayoutMenu) Select(id uint32) bool {
+	if id < klm.firstID || id-klm.firstID >= uint32(len(klm.Layouts)) {
+		return false
+	}
+	klm.Layouts[id-klm.firstID].Activate()
+	return true
+}
@@ -0,0 +1,103 @@
+package menu
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/kroppt/winmenu/internal/win"
+)
+
+var procVkKeyScanEx = win.User32.NewProc("VkKeyScanExW")
+
+// AssignMnemonics returns a copy of labels with an access key marked in every
+// label that does not have one yet, keeping the keys of a menu distinct. Keys
+// already marked with '&' are left alone. Text after a tab, the shortcut
+// column, is never used.
+//
+// Only characters that can be typed on the active keyboard layout are chosen.
+// A label without any such character, such as a Japanese label on a Japanese
+// layout, gets the first unused letter from A to Z appended in parentheses,
+// following the Windows convention for localized menus. The letter is not
+// derived from the meaning of the label, so the first such label gets "(&A)"
+// if A is free. Empty labels, used for separators, are left as they are.
+func AssignMnemonics(labels []string) []string {
+	hkl, _, _ := procGetKeyboardLayout.Call(0)
+	typeable := func(r rune) bool {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+		ret, _, _ := procVkKeyScanEx.Call(uintptr(r), hkl)
+		return int16(ret) != -1
+	}
+	used := map[rune]bool{}
+	for _, label := range labels {
+		if r, ok := mnemonic(label); ok {
+			used[unicode.ToUpper(r)] = true
+		}
+	}
+	out := make([]string, len(labels))
+	for i, label := range labels {
+		out[i] = label
+		if _, ok := mnemonic(label); ok || label == "" {
+			continue
+		}
+		text, shortcut := label, ""
+		if j := strings.IndexByte(label, '\t'); j >= 0 {
+			text, shortcut = label[:j], label[j:]
+		}
+		if pos, r, ok := pickMnemonic(text, used, typeable); ok {
+			used[unicode.ToUpper(r)] = true
+			out[i] = text[:pos] + "&" + text[pos:] + shortcut
+			continue
+		}
+		if strings.IndexFunc(text, typeable) >= 0 {
+			// Every typeable character is taken; leave the label without a key.
+			continue
+		}
+		for r := 'A'; r <= 'Z'; r++ {
+			if !used[r] {
+				used[r] = true
+				out[i] = text + "(&" + string(r) + ")" + shortcut
+				break
+			}
+		}
+	}
+	return out
+}
+
+// mnemonic returns the character marked as the access key of label.
+func mnemonic(label string) (r rune, ok bool) {
+	runes := []rune(label)
+	for i := 0; i < len(runes)-1; i++ {
+		if runes[i] != '&' {
+			continue
+		}
+		if runes[i+1] != '&' {
+			return runes[i+1], true
+		}
+		// Skip the escaped ampersand.
+		i++
+	}
+	return 0, false
+}
+
+// pickMnemonic chooses an unused typeable character of text, preferring the
+// first character of a word, and returns its byte offset.
+func pickMnemonic(text string, used map[rune]bool, typeable func(rune) bool) (pos int, r rune, ok bool) {
+	free := func(r rune) bool {
+		return typeable(r) && !used[unicode.ToUpper(r)]
+	}
+	wordStart := true
+	for i, r := range text {
+		if wordStart && free(r) {
+			return i, r, true
+		}
+		wordStart = unicode.IsSpace(r)
+	}
+	for i, r := range text {
+		if free(r) {
+			return i, r, true
+		}
+	}
+	return 0, 0, false
+}
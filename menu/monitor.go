@@ -0,0 +1,283 @@
+package menu
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/kroppt/winmenu"
+	"github.com/kroppt/winmenu/internal/win"
+)
+
+var (
+	procEnumDisplayMonitors         = win.User32.NewProc("EnumDisplayMonitors")
+	procGetMonitorInfo              = win.User32.NewProc("GetMonitorInfoW")
+	procMonitorFromWindow           = win.User32.NewProc("MonitorFromWindow")
+	procEnumDisplayDevices          = win.User32.NewProc("EnumDisplayDevicesW")
+	procGetDisplayConfigBufferSizes = win.User32.NewProc("GetDisplayConfigBufferSizes")
+	procQueryDisplayConfig          = win.User32.NewProc("QueryDisplayConfig")
+	procDisplayConfigGetDeviceInfo  = win.User32.NewProc("DisplayConfigGetDeviceInfo")
+
+	monitorEnumMu       sync.Mutex
+	monitorEnumHandles  []uintptr
+	monitorEnumCallback = syscall.NewCallback(func(hmon, hdc, lprc, lparam uintptr) uintptr {
+		monitorEnumHandles = append(monitorEnumHandles, hmon)
+		return 1
+	})
+)
+
+const (
+	monitorDefaultToNearest = 0x00000002
+	monitorInfoFPrimary     = 0x00000001
+	qdcOnlyActivePaths      = 0x00000002
+	// DISPLAYCONFIG_DEVICE_INFO_GET_SOURCE_NAME
+	displayConfigGetSourceName = 1
+	// DISPLAYCONFIG_DEVICE_INFO_GET_TARGET_NAME
+	displayConfigGetTargetName = 2
+)
+
+type monitorInfoEx struct {
+	cbSize    uint32
+	rcMonitor winmenu.Rect
+	rcWork    winmenu.Rect
+	dwFlags   uint32
+	szDevice  [32]uint16
+}
+
+type displayDevice struct {
+	cb           uint32
+	deviceName   [32]uint16
+	deviceString [128]uint16
+	stateFlags   uint32
+	deviceID     [128]uint16
+	deviceKey    [128]uint16
+}
+
+type luid struct {
+	lowPart  uint32
+	highPart int32
+}
+
+// displayConfigPathInfo is a DISPLAYCONFIG_PATH_INFO, of which only the
+// adapter and IDs of the source and target are used.
+type displayConfigPathInfo struct {
+	sourceAdapterID luid
+	sourceID        uint32
+	_               [2]uint32
+	targetAdapterID luid
+	targetID        uint32
+	_               [9]uint32
+	flags           uint32
+}
+
+// displayConfigModeInfo is a DISPLAYCONFIG_MODE_INFO, which is only passed to
+// QueryDisplayConfig as a buffer.
+type displayConfigModeInfo struct {
+	_ [8]uint64
+}
+
+type displayConfigDeviceInfoHeader struct {
+	typ       uint32
+	size      uint32
+	adapterID luid
+	id        uint32
+}
+
+type displayConfigSourceDeviceName struct {
+	header            displayConfigDeviceInfoHeader
+	viewGdiDeviceName [32]uint16
+}
+
+type displayConfigTargetDeviceName struct {
+	header                    displayConfigDeviceInfoHeader
+	flags                     uint32
+	outputTechnology          uint32
+	edidManufactureID         uint16
+	edidProductCodeID         uint16
+	connectorInstance         uint32
+	monitorFriendlyDeviceName [64]uint16
+	monitorDevicePath         [128]uint16
+}
+
+// Monitor describes a display attached to the desktop.
+type Monitor struct {
+	// The HMONITOR handle of the display.
+	Handle uintptr
+	// The GDI device name, such as \\.\DISPLAY1.
+	Device string
+	// The friendly name of the monitor, such as "DELL U2415", as shown in the
+	// display settings. It falls back to the name of the display adapter output,
+	// then to Device, if Windows does not know the monitor's name. Identical
+	// monitors have identical names.
+	Name string
+	// The resolution of the display in pixels.
+	Width, Height int
+	// Whether this is the primary display.
+	Primary bool
+}
+
+// Monitors returns the displays attached to the desktop.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-enumdisplaymonitors)
+func Monitors() (monitors []Monitor, ok bool) {
+	monitorEnumMu.Lock()
+	monitorEnumHandles = nil
+	ret, _, _ := procEnumDisplayMonitors.Call(0, 0, monitorEnumCallback, 0)
+	handles := monitorEnumHandles
+	monitorEnumHandles = nil
+	monitorEnumMu.Unlock()
+	if ret == 0 {
+		return nil, false
+	}
+	friendly := monitorFriendlyNames()
+	for _, hmon := range handles {
+		mi := monitorInfoEx{}
+		mi.cbSize = uint32(unsafe.Sizeof(mi))
+		ret, _, _ := procGetMonitorInfo.Call(hmon, uintptr(unsafe.Pointer(&mi)))
+		if ret == 0 {
+			return nil, false
+		}
+		m := Monitor{
+			Handle:  hmon,
+			Device:  syscall.UTF16ToString(mi.szDevice[:]),
+			Width:   int(mi.rcMonitor.Right - mi.rcMonitor.Left),
+			Height:  int(mi.rcMonitor.Bottom - mi.rcMonitor.Top),
+			Primary: mi.dwFlags&monitorInfoFPrimary != 0,
+		}
+		m.Name = m.Device
+		dd := displayDevice{}
+		dd.cb = uint32(unsafe.Sizeof(dd))
+		ret, _, _ = procEnumDisplayDevices.Call(uintptr(unsafe.Pointer(&mi.szDevice[0])), 0,
+			uintptr(unsafe.Pointer(&dd)), 0)
+		if ret != 0 {
+			if name := syscall.UTF16ToString(dd.deviceString[:]); name != "" {
+				m.Name = name
+			}
+		}
+		if name := friendly[m.Device]; name != "" {
+			m.Name = name
+		}
+		monitors = append(monitors, m)
+	}
+	return monitors, true
+}
+
+// monitorFriendlyNames returns the friendly monitor names of the active
+// displays by GDI device name. It returns nil if the display configuration
+// cannot be queried.
+// (https://docs.microsoft.com/en-us/windows/win32/api/wingdi/nf-wingdi-displayconfiggetdeviceinfo)
+func monitorFriendlyNames() map[string]string {
+	var numPaths, numModes uint32
+	ret, _, _ := procGetDisplayConfigBufferSizes.Call(qdcOnlyActivePaths,
+		uintptr(unsafe.Pointer(&numPaths)), uintptr(unsafe.Pointer(&numModes)))
+	if ret != 0 || numPaths == 0 {
+		return nil
+	}
+	paths := make([]displayConfigPathInfo, numPaths)
+	// One spare element keeps &modes[0] valid if there are no modes.
+	modes := make([]displayConfigModeInfo, numModes+1)
+	ret, _, _ = procQueryDisplayConfig.Call(qdcOnlyActivePaths,
+		uintptr(unsafe.Pointer(&numPaths)), uintptr(unsafe.Pointer(&paths[0])),
+		uintptr(unsafe.Pointer(&numModes)), uintptr(unsafe.Pointer(&modes[0])), 0)
+	if ret != 0 {
+		return nil
+	}
+	names := map[string]string{}
+	for _, path := range paths[:numPaths] {
+		source := displayConfigSourceDeviceName{}
+		source.header = displayConfigDeviceInfoHeader{
+			typ:       displayConfigGetSourceName,
+			size:      uint32(unsafe.Sizeof(source)),
+			adapterID: path.sourceAdapterID,
+			id:        path.sourceID,
+		}
+		ret, _, _ := procDisplayConfigGetDeviceInfo.Call(uintptr(unsafe.Pointer(&source)))
+		if ret != 0 {
+			continue
+		}
+		target := displayConfigTargetDeviceName{}
+		target.header = displayConfigDeviceInfoHeader{
+			typ:       displayConfigGetTargetName,
+			size:      uint32(unsafe.Sizeof(target)),
+			adapterID: path.targetAdapterID,
+			id:        path.targetID,
+		}
+		ret, _, _ = procDisplayConfigGetDeviceInfo.Call(uintptr(unsafe.Pointer(&target)))
+		if ret != 0 {
+			continue
+		}
+		device := syscall.UTF16ToString(source.viewGdiDeviceName[:])
+		// A cloned source has several targets; keep the first.
+		if name := syscall.UTF16ToString(target.monitorFriendlyDeviceName[:]); name != "" && names[device] == "" {
+			names[device] = name
+		}
+	}
+	return names
+}
+
+// MonitorMenu is a popup menu listing the attached displays, with the display
+// containing a window radio-checked.
+type MonitorMenu struct {
+	// The popup menu, ready to be attached with SetSubMenu.
+	Menu winmenu.HMenu
+	// The listed displays, in item order.
+	Monitors []Monitor
+	// Called by Select with the chosen display.
+	OnSelect func(Monitor)
+	firstID  uint32
+}
+
+// NewMonitorMenu creates a MonitorMenu whose items use the command IDs firstID
+// through firstID+len(Monitors)-1. The display containing hwnd is checked; hwnd
+// may be zero.
+func NewMonitorMenu(hwnd winmenu.HWnd, firstID uint32) (mm *MonitorMenu, ok bool) {
+	monitors, ok := Monitors()
+	if !ok {
+		return nil, false
+	}
+	hmenu, ok := winmenu.CreatePopupMenu()
+	if !ok {
+		return nil, false
+	}
+	var current uintptr
+	if hwnd != 0 {
+		current, _, _ = procMonitorFromWindow.Call(uintptr(hwnd), monitorDefaultToNearest)
+	}
+	count := map[string]int{}
+	for _, m := range monitors {
+		count[m.Name]++
+	}
+	for i, m := range monitors {
+		label := fmt.Sprintf("%s (%d × %d)", m.Name, m.Width, m.Height)
+		if count[m.Name] > 1 {
+			// Tell identical monitors apart by their device names.
+			label = fmt.Sprintf("%s (%s, %d × %d)", m.Name,
+				strings.TrimPrefix(m.Device, `\\.\`), m.Width, m.Height)
+		}
+		mii := winmenu.NewMenuItemInfo()
+		mii.SetAsString(label)
+		mii.SetID(firstID + uint32(i))
+		mii.SetRadioCheck()
+		if m.Handle == current {
+			mii.SetState(winmenu.MFS_CHECKED)
+		}
+		if !hmenu.InsertMenuItem(uint32(i), true, mii) {
+			hmenu.Destroy()
+			return nil, false
+		}
+	}
+	return &MonitorMenu{Menu: hmenu, Monitors: monitors, firstID: firstID}, true
+}
+
+// Select handles a WM_COMMAND ID, calling OnSelect if the ID belongs to one of
+// the menu's items. It reports whether the ID was handled.
+func (mm *MonitorMenu) Select(id uint32) bool {
+	if id < mm.firstID || id-mm.firstID >= uint32(len(mm.Monitors)) {
+		return false
+	}
+	if mm.OnSelect != nil {
+		mm.OnSelect(mm.Monitors[id-mm.firstID])
+	}
+	return true
+}
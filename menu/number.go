@@ -0,0 +1,101 @@
+package menu
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kroppt/winmenu"
+)
+
+var (
+	numberedMu sync.Mutex
+	// numbered holds the labels Renumber last set in each menu.
+	numbered = map[winmenu.HMenu]map[string]bool{}
+)
+
+// Renumber prefixes the string items of hmenu with "&1 ", "&2 ", and so on,
+// the convention for recent file and window lists, so that items can be chosen
+// by typing a digit. The tenth item gets "1&0 " and later items plain numbers.
+// Prefixes added by an earlier call are replaced, so call Renumber again after
+// inserting, removing, or reordering items; labels Renumber did not set are
+// never stripped. Separators, bitmap items, and owner-drawn items are skipped
+// and not counted. Call Forget before destroying a renumbered menu.
+func Renumber(hmenu winmenu.HMenu) (ok bool) {
+	n, _, _ := procGetMenuItemCount.Call(uintptr(hmenu))
+	if int32(n) < 0 {
+		return false
+	}
+	numberedMu.Lock()
+	defer numberedMu.Unlock()
+	prev, labels := numbered[hmenu], map[string]bool{}
+	number := 1
+	for pos := uint32(0); pos < uint32(n); pos++ {
+		mii := winmenu.NewMenuItemInfo()
+		mii.SetMask(winmenu.MIIM_STRING | winmenu.MIIM_FTYPE)
+		if !hmenu.GetMenuItemInfo(pos, true, mii) {
+			return false
+		}
+		if mii.Type()&(winmenu.MFT_SEPARATOR|winmenu.MFT_BITMAP|winmenu.MFT_OWNERDRAW) != 0 {
+			continue
+		}
+		label := numberPrefix(number) + trimNumberPrefix(mii.Text(), prev)
+		number++
+		mii.SetAsString(label)
+		if !hmenu.SetMenuItemInfo(pos, true, mii) {
+			return false
+		}
+		labels[label] = true
+	}
+	numbered[hmenu] = labels
+	return true
+}
+
+// Forget drops what Renumber remembers about hmenu and its submenus. Windows
+// reuses the handles of destroyed menus, so call it before destroying a menu
+// passed to Renumber, or a new menu could have labels stripped as if Renumber
+// had set them. DestroyQueue.Flush calls it for the menus it destroys.
+func Forget(hmenu winmenu.HMenu) {
+	numberedMu.Lock()
+	defer numberedMu.Unlock()
+	forget(hmenu)
+}
+
+func forget(hmenu winmenu.HMenu) {
+	delete(numbered, hmenu)
+	n, _, _ := procGetMenuItemCount.Call(uintptr(hmenu))
+	for pos := uint32(0); int32(n) > 0 && pos < uint32(n); pos++ {
+		if sub, ok := hmenu.SubMenu(pos); ok {
+			forget(sub)
+		}
+	}
+}
+
+// numberPrefix returns the prefix Renumber gives to the nth item.
+func numberPrefix(n int) string {
+	switch {
+	case n < 10:
+		return "&" + strconv.Itoa(n) + " "
+	case n == 10:
+		return "1&0 "
+	}
+	return strconv.Itoa(n) + " "
+}
+
+// trimNumberPrefix removes the prefix added by Renumber from label if label is
+// one of the labels Renumber set. Other labels, such as "12 Monkeys", are left
+// alone even if they start with a number.
+func trimNumberPrefix(label string, numbered map[string]bool) string {
+	if !numbered[label] {
+		return label
+	}
+	i := strings.IndexByte(label, ' ')
+	if i <= 0 {
+		return label
+	}
+	n, err := strconv.Atoi(strings.Replace(label[:i], "&", "", -1))
+	if err != nil || n <= 0 || numberPrefix(n) != label[:i+1] {
+		return label
+	}
+	return label[i+1:]
+}
@@ -0,0 +1,44 @@
+package menu
+
+import "testing"
+
+func TestTrimNumberPrefix(t *testing.T) {
+	numbered := map[string]bool{
+		"&1 Open":      true,
+		"1&0 Ten":      true,
+		"12 Monkeys":   true,
+		"&3 2 files":   true,
+		"&4 ":          true,
+		"7 Wrong form": true,
+	}
+	tests := []struct {
+		label    string
+		numbered map[string]bool
+		want     string
+	}{
+		{"&1 Open", numbered, "Open"},
+		{"1&0 Ten", numbered, "Ten"},
+		{"12 Monkeys", numbered, "Monkeys"},
+		{"&3 2 files", numbered, "2 files"},
+		{"&4 ", numbered, ""},
+		// Labels Renumber did not set are kept.
+		{"12 Monkeys", nil, "12 Monkeys"},
+		{"&1 Open", nil, "&1 Open"},
+		{"2 files", numbered, "2 files"},
+		// Prefixes Renumber would not produce are kept.
+		{"7 Wrong form", numbered, "7 Wrong form"},
+	}
+	for _, tt := range tests {
+		if got := trimNumberPrefix(tt.label, tt.numbered); got != tt.want {
+			t.Errorf("trimNumberPrefix(%q) = %q, want %q", tt.label, got, tt.want)
+		}
+	}
+}
+
+func TestNumberPrefix(t *testing.T) {
+	for n, want := range map[int]string{1: "&1 ", 9: "&9 ", 10: "1&0 ", 11: "11 ", 123: "123 "} {
+		if got := numberPrefix(n); got != want {
+			t.Errorf("numberPrefix(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package menu
+
+import "github.com/kroppt/winmenu"
+
+// PopupRequest describes a popup menu to show with Popups.Show. The fields are
+// the arguments of TrackPopup.
+type PopupRequest struct {
+	Menu  winmenu.HMenu
+	Flags winmenu.TrackFlag
+	X, Y  int32
+	Owner winmenu.HWnd
+	// If not nil, called with the results of TrackPopup once the menu closes.
+	Done func(cmd uint32, ok bool)
+}
+
+// Popups shows popup menus one at a time. Windows allows only one popup menu
+// per thread unless the nested one is shown with TPM_RECURSE, so a popup
+// requested while another is open, for example from a timer or a WM_COMMAND
+// sent during the menu loop, would otherwise fail. A Popups must only be used
+// from the thread that owns the menus' windows.
+type Popups struct {
+	// Whether a popup requested while another is open is shown on top of it
+	// with TPM_RECURSE. By default it is queued and shown after the open popups
+	// close, which is safer, as the open menu's handlers may not expect to be
+	// re-entered.
+	Recurse bool
+	open    int
+	queue   []PopupRequest
+}
+
+// Show shows the popup menu described by req, or queues it if another popup is
+// open and Recurse is false. It returns false if the menu could not be shown.
+// Once the menu closes, req.Done is called, so the results of a queued popup
+// are delivered after Show has returned.
+func (p *Popups) Show(req PopupRequest) (ok bool) {
+	if p.open > 0 && !p.Recurse {
+		p.queue = append(p.queue, req)
+		return true
+	}
+	if p.open > 0 {
+		req.Flags |= winmenu.TPM_RECURSE
+	}
+	ok = p.track(req)
+	if p.open == 0 {
+		for len(p.queue) > 0 {
+			next := p.queue[0]
+			p.queue = p.queue[1:]
+			p.track(next)
+		}
+	}
+	return ok
+}
+
+// Pending returns the number of queued popups.
+func (p *Popups) Pending() int {
+	return len(p.queue)
+}
+
+func (p *Popups) track(req PopupRequest) (ok bool) {
+	p.open++
+	cmd, ok := req.Menu.TrackPopup(req.Flags, req.X, req.Y, req.Owner)
+	p.open--
+	if req.Done != nil {
+		req.Done(cmd, ok)
+	}
+	return ok
+}
@@ -0,0 +1,159 @@
+package menu
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/kroppt/winmenu"
+	"github.com/kroppt/winmenu/internal/win"
+)
+
+var (
+	procOpenProcessToken      = win.Advapi32.NewProc("OpenProcessToken")
+	procLookupPrivilegeValue  = win.Advapi32.NewProc("LookupPrivilegeValueW")
+	procAdjustTokenPrivileges = win.Advapi32.NewProc("AdjustTokenPrivileges")
+	procGetCurrentProcess     = win.Kernel32.NewProc("GetCurrentProcess")
+	procCloseHandle           = win.Kernel32.NewProc("CloseHandle")
+	procSetSuspendState       = win.Powrprof.NewProc("SetSuspendState")
+	procLockWorkStation       = win.User32.NewProc("LockWorkStation")
+	procExitWindowsEx         = win.User32.NewProc("ExitWindowsEx")
+)
+
+const (
+	tokenAdjustPrivileges = 0x0020
+	tokenQuery            = 0x0008
+	sePrivilegeEnabled    = 0x00000002
+	ewxReboot             = 0x00000002
+	ewxPowerOff           = 0x00000008
+	// SHTDN_REASON_MAJOR_OTHER | SHTDN_REASON_MINOR_OTHER | SHTDN_REASON_FLAG_PLANNED
+	shutdownReasonPlanned = 0x80000000
+)
+
+type tokenPrivileges struct {
+	privilegeCount uint32
+	luidLowPart    uint32
+	luidHighPart   int32
+	attributes     uint32
+}
+
+// PowerAction is a system power or session action.
+type PowerAction int
+
+// The actions offered by a PowerMenu, in item order.
+const (
+	// Locks the workstation.
+	PowerLock PowerAction = iota
+	// Puts the computer to sleep.
+	PowerSleep
+	// Restarts the computer.
+	PowerRestart
+	// Shuts down and powers off the computer.
+	PowerShutDown
+)
+
+// String returns the menu label of the action.
+func (pa PowerAction) String() string {
+	switch pa {
+	case PowerLock:
+		return "Lock"
+	case PowerSleep:
+		return "Sleep"
+	case PowerRestart:
+		return "Restart"
+	case PowerShutDown:
+		return "Shut down"
+	}
+	return ""
+}
+
+// Do performs the action, enabling the shutdown privilege when the action
+// needs it.
+func (pa PowerAction) Do() (ok bool) {
+	var ret uintptr
+	switch pa {
+	case PowerLock:
+		ret, _, _ = procLockWorkStation.Call()
+	case PowerSleep:
+		if !enableShutdownPrivilege() {
+			return false
+		}
+		ret, _, _ = procSetSuspendState.Call(0, 0, 0)
+		// SetSuspendState returns a BOOLEAN, so only the low byte is defined.
+		ret = uintptr(byte(ret))
+	case PowerRestart:
+		if !enableShutdownPrivilege() {
+			return false
+		}
+		ret, _, _ = procExitWindowsEx.Call(ewxReboot, shutdownReasonPlanned)
+	case PowerShutDown:
+		if !enableShutdownPrivilege() {
+			return false
+		}
+		ret, _, _ = procExitWindowsEx.Call(ewxPowerOff, shutdownReasonPlanned)
+	}
+	return ret != 0
+}
+
+func enableShutdownPrivilege() (ok bool) {
+	process, _, _ := procGetCurrentProcess.Call()
+	var token uintptr
+	ret, _, _ := procOpenProcessToken.Call(process, tokenAdjustPrivileges|tokenQuery,
+		uintptr(unsafe.Pointer(&token)))
+	if ret == 0 {
+		return false
+	}
+	defer procCloseHandle.Call(token)
+	tp := tokenPrivileges{privilegeCount: 1, attributes: sePrivilegeEnabled}
+	name := syscall.StringToUTF16Ptr("SeShutdownPrivilege")
+	ret, _, _ = procLookupPrivilegeValue.Call(0, uintptr(unsafe.Pointer(name)),
+		uintptr(unsafe.Pointer(&tp.luidLowPart)))
+	if ret == 0 {
+		return false
+	}
+	ret, _, err := procAdjustTokenPrivileges.Call(token, 0, uintptr(unsafe.Pointer(&tp)), 0, 0, 0)
+	// AdjustTokenPrivileges succeeds even if the privilege was not assigned.
+	return ret != 0 && err == syscall.Errno(0)
+}
+
+// PowerMenu is a popup menu offering the PowerAction values.
+type PowerMenu struct {
+	// The popup menu, ready to be attached with SetSubMenu.
+	Menu winmenu.HMenu
+	// Called by Select before performing an action. The action is skipped
+	// unless it returns true. A nil Confirm performs actions unconditionally.
+	Confirm func(PowerAction) bool
+	firstID uint32
+}
+
+// NewPowerMenu creates a PowerMenu whose items use the command IDs firstID
+// through firstID+PowerShutDown.
+func NewPowerMenu(firstID uint32) (pm *PowerMenu, ok bool) {
+	hmenu, ok := winmenu.CreatePopupMenu()
+	if !ok {
+		return nil, false
+	}
+	for pa := PowerLock; pa <= PowerShutDown; pa++ {
+		mii := winmenu.NewMenuItemInfo()
+		mii.SetAsString(pa.String())
+		mii.SetID(firstID + uint32(pa))
+		if !hmenu.InsertMenuItem(uint32(pa), true, mii) {
+			hmenu.Destroy()
+			return nil, false
+		}
+	}
+	return &PowerMenu{Menu: hmenu, firstID: firstID}, true
+}
+
+// Select handles a WM_COMMAND ID, confirming and performing the chosen action
+// if the ID belongs to one of the menu's items. It reports whether the ID was
+// handled.
+func (pm *PowerMenu) Select(id uint32) bool {
+	if id < pm.firstID || id-pm.firstID > uint32(PowerShutDown) {
+		return false
+	}
+	pa := PowerAction(id - pm.firstID)
+	if pm.Confirm == nil || pm.Confirm(pa) {
+		pa.Do()
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+package menu
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/kroppt/winmenu"
+	"github.com/kroppt/winmenu/internal/win"
+)
+
+var (
+	procEnumPrinters      = win.Winspool.NewProc("EnumPrintersW")
+	procGetDefaultPrinter = win.Winspool.NewProc("GetDefaultPrinterW")
+	procSetDefaultPrinter = win.Winspool.NewProc("SetDefaultPrinterW")
+)
+
+const (
+	printerEnumLocal       = 0x00000002
+	printerEnumConnections = 0x00000004
+)
+
+type printerInfo4 struct {
+	pPrinterName *uint16
+	pServerName  *uint16
+	attributes   uint32
+}
+
+// Printer describes an installed printer.
+type Printer struct {
+	// The printer name.
+	Name string
+	// Whether this is the user's default printer.
+	Default bool
+}
+
+// Printers returns the local and connected printers.
+// (https://docs.microsoft.com/en-us/windows/win32/printdocs/enumprinters)
+func Printers() (printers []Printer, ok bool) {
+	var needed, returned uint32
+	procEnumPrinters.Call(printerEnumLocal|printerEnumConnections, 0, 4, 0, 0,
+		uintptr(unsafe.Pointer(&needed)), uintptr(unsafe.Pointer(&returned)))
+	if needed == 0 {
+		// No printers installed.
+		return nil, true
+	}
+	// Allocated as uintptr so the PRINTER_INFO_4 array is aligned.
+	buf := make([]uintptr, (uintptr(needed)+unsafe.Sizeof(uintptr(0))-1)/unsafe.Sizeof(uintptr(0)))
+	ret, _, _ := procEnumPrinters.Call(printerEnumLocal|printerEnumConnections, 0, 4,
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(needed),
+		uintptr(unsafe.Pointer(&needed)), uintptr(unsafe.Pointer(&returned)))
+	if ret == 0 {
+		return nil, false
+	}
+	def := defaultPrinter()
+	for _, pi := range unsafe.Slice((*printerInfo4)(unsafe.Pointer(&buf[0])), returned) {
+		name := utf16PtrToString(pi.pPrinterName)
+		printers = append(printers, Printer{Name: name, Default: name == def})
+	}
+	return printers, true
+}
+
+func defaultPrinter() string {
+	var n uint32
+	procGetDefaultPrinter.Call(0, uintptr(unsafe.Pointer(&n)))
+	if n == 0 {
+		return ""
+	}
+	buf := make([]uint16, n)
+	ret, _, _ := procGetDefaultPrinter.Call(uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&n)))
+	if ret == 0 {
+		return ""
+	}
+	return syscall.UTF16ToString(buf)
+}
+
+// utf16PtrToString returns the NUL-terminated UTF-16 string at p.
+func utf16PtrToString(p *uint16) string {
+	if p == nil {
+		return ""
+	}
+	n := 0
+	for ptr := unsafe.Pointer(p); *(*uint16)(ptr) != 0; n++ {
+		ptr = unsafe.Add(ptr, 2)
+	}
+	return syscall.UTF16ToString(unsafe.Slice(p, n))
+}
+
+// SetDefault makes the printer the user's default printer.
+// (https://docs.microsoft.com/en-us/windows/win32/printdocs/setdefaultprinter)
+func (p Printer) SetDefault() (ok bool) {
+	name, err := syscall.UTF16PtrFromString(p.Name)
+	if err != nil {
+		return false
+	}
+	ret, _, _ := procSetDefaultPrinter.Call(uintptr(unsafe.Pointer(name)))
+	return ret != 0
+}
+
+// PrinterMenu is a popup menu listing the installed printers, with the default
+// printer radio-checked.
+type PrinterMenu struct {
+	// The popup menu, ready to be attached with SetSubMenu.
+	Menu winmenu.HMenu
+	// The listed printers, in item order.
+	Printers []Printer
+	// Called by Select with the chosen printer. If nil, Select makes the
+	// chosen printer the default instead.
+	OnSelect func(Printer)
+	firstID  uint32
+}
+
+// NewPrinterMenu creates a PrinterMenu whose items use the command IDs firstID
+// through firstID+len(Printers)-1.
+func NewPrinterMenu(firstID uint32) (pm *PrinterMenu, ok bool) {
+	printers, ok := Printers()
+	if !ok {
+		return nil, false
+	}
+	hmenu, ok := winmenu.CreatePopupMenu()
+	if !ok {
+		return nil, false
+	}
+	for i, p := range printers {
+		mii := winmenu.NewMenuItemInfo()
+		mii.SetAsString(itemLabel(p.Name, 0))
+		mii.SetID(firstID + uint32(i))
+		mii.SetRadioCheck()
+		if p.Default {
+			mii.SetState(winmenu.MFS_CHECKED)
+		}
+		if !hmenu.InsertMenuItem(uint32(i), true, mii) {
+			hmenu.Destroy()
+			return nil, false
+		}
+	}
+	return &PrinterMenu{Menu: hmenu, Printers: printers, firstID: firstID}, true
+}
+
+// Select handles a WM_COMMAND ID, calling OnSelect or setting the default
+// printer if the ID belongs to one of the menu's items. It reports whether the
+// ID was handled.
+func (pm *PrinterMenu) Select(id uint32) bool {
+	if id < pm.firstID || id-pm.firstID >= uint32(len(pm.Printers)) {
+		return false
+	}
+	p := pm.Printers[id-pm.firstID]
+	if pm.OnSelect != nil {
+		pm.OnSelect(p)
+	} else {
+		p.SetDefault()
+	}
+	return true
+}
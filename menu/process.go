@@ -0,0 +1,198 @@
+package menu
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/kroppt/winmenu"
+	"github.com/kroppt/winmenu/internal/win"
+)
+
+var (
+	procCreateToolhelp32Snapshot  = win.Kernel32.NewProc("CreateToolhelp32Snapshot")
+	procProcess32First            = win.Kernel32.NewProc("Process32FirstW")
+	procProcess32Next             = win.Kernel32.NewProc("Process32NextW")
+	procOpenProcess               = win.Kernel32.NewProc("OpenProcess")
+	procQueryFullProcessImageName = win.Kernel32.NewProc("QueryFullProcessImageNameW")
+	procExtractIconEx             = win.Shell32.NewProc("ExtractIconExW")
+	procDestroyIcon               = win.User32.NewProc("DestroyIcon")
+)
+
+const (
+	th32csSnapProcess              = 0x00000002
+	processQueryLimitedInformation = 0x1000
+	invalidHandleValue             = ^uintptr(0)
+	maxPath                        = 260
+	errorInsufficientBuffer        = 122
+)
+
+type processEntry32 struct {
+	dwSize              uint32
+	cntUsage            uint32
+	th32ProcessID       uint32
+	th32DefaultHeapID   uintptr
+	th32ModuleID        uint32
+	cntThreads          uint32
+	th32ParentProcessID uint32
+	pcPriClassBase      int32
+	dwFlags             uint32
+	szExeFile           [maxPath]uint16
+}
+
+// Process describes a running process.
+type Process struct {
+	// The process identifier.
+	PID uint32
+	// The executable file name, such as notepad.exe.
+	Name string
+	// The full path of the executable, or empty if the process could not be
+	// queried.
+	Path string
+}
+
+// Processes returns the running processes sorted by name.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/tlhelp32/nf-tlhelp32-createtoolhelp32snapshot)
+func Processes() (processes []Process, ok bool) {
+	snapshot, _, _ := procCreateToolhelp32Snapshot.Call(th32csSnapProcess, 0)
+	if snapshot == invalidHandleValue {
+		return nil, false
+	}
+	defer procCloseHandle.Call(snapshot)
+	pe := processEntry32{}
+	pe.dwSize = uint32(unsafe.Sizeof(pe))
+	buf := make([]uint16, maxPath)
+	ret, _, _ := procProcess32First.Call(snapshot, uintptr(unsafe.Pointer(&pe)))
+	for ; ret != 0; ret, _, _ = procProcess32Next.Call(snapshot, uintptr(unsafe.Pointer(&pe))) {
+		processes = append(processes, Process{
+			PID:  pe.th32ProcessID,
+			Name: syscall.UTF16ToString(pe.szExeFile[:]),
+			Path: processPath(pe.th32ProcessID, &buf),
+		})
+	}
+	sort.Slice(processes, func(i, j int) bool {
+		return strings.ToLower(processes[i].Name) < strings.ToLower(processes[j].Name)
+	})
+	return processes, true
+}
+
+// processPath returns the executable path of the process, using *buf as the
+// buffer and growing it if the path does not fit.
+func processPath(pid uint32, buf *[]uint16) string {
+	h, _, _ := procOpenProcess.Call(processQueryLimitedInformation, 0, uintptr(pid))
+	if h == 0 {
+		return ""
+	}
+	defer procCloseHandle.Call(h)
+	for {
+		n := uint32(len(*buf))
+		ret, _, err := procQueryFullProcessImageName.Call(h, 0, uintptr(unsafe.Pointer(&(*buf)[0])),
+			uintptr(unsafe.Pointer(&n)))
+		if ret != 0 {
+			return syscall.UTF16ToString((*buf)[:n])
+		}
+		// Paths are limited to 32767 characters.
+		if err != syscall.Errno(errorInsufficientBuffer) || len(*buf) >= 32*1024 {
+			return ""
+		}
+		*buf = make([]uint16, 2*len(*buf))
+	}
+}
+
+// ProcessMenu is a popup menu listing the running processes with their icons,
+// names, and process identifiers.
+type ProcessMenu struct {
+	// The popup menu, ready to be attached with SetSubMenu.
+	Menu winmenu.HMenu
+	// The listed processes, in item order.
+	Processes []Process
+	// Called by Select with the chosen process.
+	OnSelect func(Process)
+	// The maximum number of processes listed, or zero for no limit.
+	Max     int
+	icons   map[string]winmenu.HBitmap
+	firstID uint32
+}
+
+// NewProcessMenu creates an empty ProcessMenu whose items use the command IDs
+// firstID through firstID+max-1, or from firstID on if max is zero. The menu
+// is filled by Refresh, typically from the WM_INITMENUPOPUP handler so the
+// list is current whenever it opens.
+func NewProcessMenu(firstID uint32, max int) (pm *ProcessMenu, ok bool) {
+	hmenu, ok := winmenu.CreatePopupMenu()
+	if !ok {
+		return nil, false
+	}
+	return &ProcessMenu{Menu: hmenu, Max: max, icons: map[string]winmenu.HBitmap{}, firstID: firstID}, true
+}
+
+// Refresh replaces the menu items with the currently running processes.
+// Icons are cached by executable path until Release.
+func (pm *ProcessMenu) Refresh() (ok bool) {
+	processes, ok := Processes()
+	if !ok || !clearMenu(pm.Menu) {
+		return false
+	}
+	if pm.Max > 0 && len(processes) > pm.Max {
+		processes = processes[:pm.Max]
+	}
+	pm.Processes = processes
+	for i, p := range processes {
+		mii := winmenu.NewMenuItemInfo()
+		mii.SetAsString(itemLabel(fmt.Sprintf("%s (%d)", p.Name, p.PID), 0))
+		mii.SetID(pm.firstID + uint32(i))
+		if hbm := pm.icon(p.Path); hbm != 0 {
+			mii.SetItemBitmap(hbm)
+		}
+		if !pm.Menu.InsertMenuItem(uint32(i), true, mii) {
+			return false
+		}
+	}
+	return true
+}
+
+func (pm *ProcessMenu) icon(path string) winmenu.HBitmap {
+	if path == "" {
+		return 0
+	}
+	if hbm, ok := pm.icons[path]; ok {
+		return hbm
+	}
+	var hbm winmenu.HBitmap
+	var hicon winmenu.HIcon
+	ret, _, _ := procExtractIconEx.Call(uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(path))), 0, 0,
+		uintptr(unsafe.Pointer(&hicon)), 1)
+	if ret != 0 && hicon != 0 {
+		size, _, _ := procGetSystemMetrics.Call(smCXSmIcon)
+		hbm, _ = BitmapFromIcon(hicon, int(size))
+		procDestroyIcon.Call(uintptr(hicon))
+	}
+	// Executables without an icon are cached too, as zero.
+	pm.icons[path] = hbm
+	return hbm
+}
+
+// Select handles a WM_COMMAND ID, calling OnSelect if the ID belongs to one of
+// the menu's items. It reports whether the ID was handled.
+func (pm *ProcessMenu) Select(id uint32) bool {
+	if id < pm.firstID || id-pm.firstID >= uint32(len(pm.Processes)) {
+		return false
+	}
+	if pm.OnSelect != nil {
+		pm.OnSelect(pm.Processes[id-pm.firstID])
+	}
+	return true
+}
+
+// Release deletes the cached icon bitmaps. Call it once the menu is no longer
+// displayed.
+func (pm *ProcessMenu) Release() {
+	for path, hbm := range pm.icons {
+		if hbm != 0 {
+			hbm.Delete()
+		}
+		delete(pm.icons, path)
+	}
+}
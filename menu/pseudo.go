@@ -0,0 +1,85 @@
+package menu
+
+import (
+	"strings"
+
+	"github.com/kroppt/winmenu"
+)
+
+// accented maps ASCII letters to accented look-alikes for PseudoLocalize.
+var accented = map[rune]rune{
+	'A': 'Å', 'C': 'Ç', 'D': 'Ð', 'E': 'É', 'G': 'Ĝ', 'H': 'Ĥ', 'I': 'Î', 'J': 'Ĵ',
+	'K': 'Ķ', 'L': 'Ļ', 'N': 'Ñ', 'O': 'Ö', 'R': 'Ŕ', 'S': 'Š', 'T': 'Ţ', 'U': 'Û',
+	'W': 'Ŵ', 'Y': 'Ý', 'Z': 'Ž',
+	'a': 'å', 'c': 'ç', 'd': 'ð', 'e': 'é', 'g': 'ĝ', 'h': 'ĥ', 'i': 'î', 'j': 'ĵ',
+	'k': 'ķ', 'l': 'ļ', 'n': 'ñ', 'o': 'ö', 'r': 'ŕ', 's': 'š', 't': 'ţ', 'u': 'û',
+	'w': 'ŵ', 'y': 'ý', 'z': 'ž',
+}
+
+// PseudoLocalize returns label with its letters swapped for accented
+// look-alikes, padded to about 140% of its length and wrapped in brackets, as
+// in "[Šåvé &Aš ~~~~]". Labels shown this way before real translations arrive
+// reveal text that is truncated, clipped, or not loaded from resources. The
+// access key character after '&' and the shortcut text after a tab are kept as
+// they are, so mnemonics and accelerators still work.
+func PseudoLocalize(label string) string {
+	if label == "" {
+		return ""
+	}
+	text, shortcut := label, ""
+	if i := strings.IndexByte(label, '\t'); i >= 0 {
+		text, shortcut = label[:i], label[i:]
+	}
+	var b strings.Builder
+	b.WriteByte('[')
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '&' && i+1 < len(runes) {
+			// Keep the marker and the marked character, or the escaped ampersand.
+			b.WriteRune(r)
+			b.WriteRune(runes[i+1])
+			i++
+			continue
+		}
+		if a, ok := accented[r]; ok {
+			r = a
+		}
+		b.WriteRune(r)
+	}
+	pad := (len(runes)*4 + 9) / 10
+	if pad < 2 {
+		pad = 2
+	}
+	b.WriteByte(' ')
+	b.WriteString(strings.Repeat("~", pad))
+	b.WriteByte(']')
+	b.WriteString(shortcut)
+	return b.String()
+}
+
+// PseudoLocalizeMenu applies PseudoLocalize to the text of every string item of
+// hmenu and its submenus.
+func PseudoLocalizeMenu(hmenu winmenu.HMenu) (ok bool) {
+	n, _, _ := procGetMenuItemCount.Call(uintptr(hmenu))
+	if int32(n) < 0 {
+		return false
+	}
+	for pos := uint32(0); pos < uint32(n); pos++ {
+		mii := winmenu.NewMenuItemInfo()
+		mii.SetMask(winmenu.MIIM_STRING | winmenu.MIIM_FTYPE | winmenu.MIIM_SUBMENU)
+		if !hmenu.GetMenuItemInfo(pos, true, mii) {
+			return false
+		}
+		if sub := mii.SubMenu(); sub != 0 && !PseudoLocalizeMenu(sub) {
+			return false
+		}
+		if text := mii.Text(); text != "" {
+			mii.SetAsString(PseudoLocalize(text))
+			if !hmenu.SetMenuItemInfo(pos, true, mii) {
+				return false
+			}
+		}
+	}
+	return true
+}
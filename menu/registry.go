@@ -0,0 +1,290 @@
+package menu
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kroppt/winmenu"
+	"github.com/kroppt/winmenu/internal/win"
+)
+
+var (
+	procPostMessage = win.User32.NewProc("PostMessageW")
+	errNoHostIDs    = errors.New("winmenu: no plugin command IDs left")
+)
+
+// NavigationGroup is the group placed before all other groups of a location.
+const NavigationGroup = "navigation"
+
+// DefaultPluginIDBase is the first command ID a new Registry hands out to
+// plugin items.
+const DefaultPluginIDBase = 0x8000
+
+// Contribution is a menu item contributed to a named menu location.
+type Contribution struct {
+	// The item text.
+	Text string
+	// The command ID sent with WM_COMMAND when the item is chosen.
+	ID uint32
+	// The group of the item. Groups are sorted by name, except that
+	// NavigationGroup comes first, and are divided by separators.
+	Group string
+	// The position of the item within its group. Lower values come first, and
+	// items with equal values keep the order they were contributed in.
+	Order int
+}
+
+// Registry collects menu items contributed by independent modules to named
+// menu locations, such as "context/editor" or "tray/main", and composes the
+// menus for those locations.
+type Registry struct {
+	// The first command ID handed out to plugin items. Change it before the
+	// first plugin contribution. IDs are handed out up to 0xFFFF.
+	PluginIDBase uint32
+	// The maximum number of items, counting separators, in a menu created by
+	// Build, or zero for no limit. Items beyond it are moved to a submenu at the
+	// end of the menu, labeled MoreText, which is split the same way.
+	MaxItems int
+	// The maximum nesting depth of the submenus created for MaxItems, or zero
+	// for no limit. Build fails if the items do not fit.
+	MaxDepth int
+	// The label of the submenus created for MaxItems.
+	MoreText  string
+	mu        sync.Mutex
+	locations map[string][]Contribution
+	nextID    uint32
+	hostIDs   map[pluginID]uint32
+	pluginIDs map[uint32]pluginID
+}
+
+type pluginID struct {
+	plugin string
+	id     uint32
+}
+
+// NewRegistry returns a pointer to a new, empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		PluginIDBase: DefaultPluginIDBase,
+		MoreText:     "&More",
+		locations:    map[string][]Contribution{},
+		hostIDs:      map[pluginID]uint32{},
+		pluginIDs:    map[uint32]pluginID{},
+	}
+}
+
+// Contribute adds an item to the given location. It is safe to call from
+// multiple goroutines.
+func (r *Registry) Contribute(location string, c Contribution) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.locations[location] = append(r.locations[location], c)
+}
+
+// Contributions returns the items of the given location in menu order.
+func (r *Registry) Contributions(location string) []Contribution {
+	r.mu.Lock()
+	items := append([]Contribution(nil), r.locations[location]...)
+	r.mu.Unlock()
+	sort.SliceStable(items, func(i, j int) bool {
+		gi, gj := items[i].Group, items[j].Group
+		if gi != gj {
+			if gi == NavigationGroup || gj == NavigationGroup {
+				return gi == NavigationGroup
+			}
+			return gi < gj
+		}
+		return items[i].Order < items[j].Order
+	})
+	return items
+}
+
+// Build creates a popup menu holding the items of the given location, with a
+// separator between groups. See MaxItems for how long menus are split.
+func (r *Registry) Build(location string) (hmenu winmenu.HMenu, ok bool) {
+	var entries []Contribution
+	items := r.Contributions(location)
+	for i, c := range items {
+		if i > 0 && c.Group != items[i-1].Group {
+			// A zero Contribution stands for a separator.
+			entries = append(entries, Contribution{})
+		}
+		entries = append(entries, c)
+	}
+	pages, ok := r.paginate(entries)
+	if !ok {
+		return 0, false
+	}
+	return buildPages(pages, r.MoreText)
+}
+
+// paginate splits entries into the menus created by Build. Every page but the
+// last holds MaxItems-1 entries, leaving room for the MoreText submenu that
+// opens the next page, and separators at either end of a split are dropped.
+// It returns false if the pages would nest deeper than MaxDepth.
+func (r *Registry) paginate(entries []Contribution) (pages [][]Contribution, ok bool) {
+	for depth := 0; ; depth++ {
+		if r.MaxDepth > 0 && depth > r.MaxDepth {
+			return nil, false
+		}
+		max := r.MaxItems
+		if max <= 0 || len(entries) <= max {
+			return append(pages, entries), true
+		}
+		if max < 2 {
+			max = 2
+		}
+		page, rest := trimSeparators(entries[:max-1]), trimSeparators(entries[max-1:])
+		pages = append(pages, page)
+		if len(rest) == 0 {
+			return pages, true
+		}
+		entries = rest
+	}
+}
+
+// buildPages creates a popup menu holding the first page, with the following
+// pages in nested submenus labeled moreText.
+func buildPages(pages [][]Contribution, moreText string) (hmenu winmenu.HMenu, ok bool) {
+	hmenu, ok = winmenu.CreatePopupMenu()
+	if !ok {
+		return 0, false
+	}
+	for _, c := range pages[0] {
+		flags := winmenu.MF_STRING
+		if c.Text == "" {
+			flags = winmenu.MF_SEPARATOR
+		}
+		if !hmenu.AppendMenu(flags, uintptr(c.ID), c.Text) {
+			hmenu.Destroy()
+			return 0, false
+		}
+	}
+	if len(pages) > 1 {
+		more, ok := buildPages(pages[1:], moreText)
+		if !ok {
+			hmenu.Destroy()
+			return 0, false
+		}
+		if !hmenu.AppendMenu(winmenu.MF_POPUP, uintptr(more), moreText) {
+			more.Destroy()
+			hmenu.Destroy()
+			return 0, false
+		}
+	}
+	return hmenu, true
+}
+
+// trimSeparators removes the separators at either end of entries.
+func trimSeparators(entries []Contribution) []Contribution {
+	for len(entries) > 0 && entries[0].Text == "" {
+		entries = entries[1:]
+	}
+	for len(entries) > 0 && entries[len(entries)-1].Text == "" {
+		entries = entries[:len(entries)-1]
+	}
+	return entries
+}
+
+// Plugin is a command ID namespace within a Registry. Items contributed through
+// it keep plugin-local IDs, which the registry remaps to unique host IDs so that
+// plugins cannot collide with each other.
+type Plugin struct {
+	r    *Registry
+	name string
+}
+
+// Plugin returns the namespace of the named plugin.
+func (r *Registry) Plugin(name string) *Plugin {
+	return &Plugin{r: r, name: name}
+}
+
+// Contribute adds an item to the given location, remapping its plugin-local ID
+// to a host ID. It fails if no host ID is left.
+func (p *Plugin) Contribute(location string, c Contribution) error {
+	id, err := p.HostID(c.ID)
+	if err != nil {
+		return err
+	}
+	c.ID = id
+	p.r.Contribute(location, c)
+	return nil
+}
+
+// HostID returns the host command ID of the plugin-local ID, allocating one on
+// first use. The same local ID always maps to the same host ID. WM_COMMAND
+// carries only the low word of a command ID, so allocation fails once the
+// host IDs would go past 0xFFFF.
+func (p *Plugin) HostID(id uint32) (uint32, error) {
+	p.r.mu.Lock()
+	defer p.r.mu.Unlock()
+	key := pluginID{plugin: p.name, id: id}
+	if host, ok := p.r.hostIDs[key]; ok {
+		return host, nil
+	}
+	if uint64(p.r.PluginIDBase)+uint64(p.r.nextID) > 0xFFFF {
+		return 0, errNoHostIDs
+	}
+	host := p.r.PluginIDBase + p.r.nextID
+	p.r.nextID++
+	p.r.hostIDs[key] = host
+	p.r.pluginIDs[host] = key
+	return host, nil
+}
+
+// Resolve maps a host command ID, such as one received with WM_COMMAND, back to
+// the plugin that contributed it and its plugin-local ID.
+func (r *Registry) Resolve(hostID uint32) (plugin string, id uint32, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.pluginIDs[hostID]
+	return key.plugin, key.id, ok
+}
+
+// InvokeItem posts WM_COMMAND to hwnd for the item at path, as if the user had
+// chosen it, without opening any menu. The path is a location followed by the
+// item text, such as "context/editor/Copy"; mnemonic ampersands and shortcut
+// text after a tab are ignored when matching the item text. It returns false if
+// no item matches or the message cannot be posted.
+func (r *Registry) InvokeItem(hwnd winmenu.HWnd, path string) (ok bool) {
+	c, ok := r.lookup(path)
+	if !ok {
+		return false
+	}
+	ret, _, _ := procPostMessage.Call(uintptr(hwnd), winmenu.WM_COMMAND, uintptr(c.ID), 0)
+	return ret != 0
+}
+
+// lookup finds the item at path, preferring the longest matching location.
+func (r *Registry) lookup(path string) (c Contribution, ok bool) {
+	r.mu.Lock()
+	var locations []string
+	for location := range r.locations {
+		if strings.HasPrefix(path, location+"/") {
+			locations = append(locations, location)
+		}
+	}
+	r.mu.Unlock()
+	sort.Slice(locations, func(i, j int) bool { return len(locations[i]) > len(locations[j]) })
+	for _, location := range locations {
+		text := path[len(location)+1:]
+		for _, c := range r.Contributions(location) {
+			if plainText(c.Text) == text {
+				return c, true
+			}
+		}
+	}
+	return Contribution{}, false
+}
+
+// plainText strips the shortcut text and mnemonic markers from item text.
+func plainText(text string) string {
+	if i := strings.IndexByte(text, '\t'); i >= 0 {
+		text = text[:i]
+	}
+	text = strings.Replace(text, "&&", "\x00", -1)
+	text = strings.Replace(text, "&", "", -1)
+	return strings.Replace(text, "\x00", "&", -1)
+}
@@ -0,0 +1,159 @@
+package menu
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPluginHostID(t *testing.T) {
+	r := NewRegistry()
+	r.PluginIDBase = 0xFFFE
+	a, b := r.Plugin("a"), r.Plugin("b")
+	if id, err := a.HostID(1); err != nil || id != 0xFFFE {
+		t.Fatalf("a.HostID(1) = %#x, %v, want 0xfffe", id, err)
+	}
+	if id, err := b.HostID(1); err != nil || id != 0xFFFF {
+		t.Fatalf("b.HostID(1) = %#x, %v, want 0xffff", id, err)
+	}
+	if id, err := a.HostID(1); err != nil || id != 0xFFFE {
+		t.Errorf("a.HostID(1) again = %#x, %v, want 0xfffe", id, err)
+	}
+	if _, err := a.HostID(2); err == nil {
+		t.Error("a.HostID(2) past 0xffff succeeded")
+	}
+	if err := b.Contribute("tray/main", Contribution{Text: "Quit", ID: 2}); err == nil {
+		t.Error("Contribute past 0xffff succeeded")
+	}
+	if plugin, id, ok := r.Resolve(0xFFFF); !ok || plugin != "b" || id != 1 {
+		t.Errorf("Resolve(0xffff) = %q, %d, %t, want \"b\", 1, true", plugin, id, ok)
+	}
+}
+
+// entries parses a space-separated list of item texts, with "-" standing for a
+// separator.
+func entries(list string) []Contribution {
+	var cs []Contribution
+	for _, text := range strings.Fields(list) {
+		if text == "-" {
+			text = ""
+		}
+		cs = append(cs, Contribution{Text: text})
+	}
+	return cs
+}
+
+// numberedEntries returns n items with the texts 1 through n.
+func numberedEntries(n int) string {
+	texts := make([]string, n)
+	for i := range texts {
+		texts[i] = strconv.Itoa(i + 1)
+	}
+	return strings.Join(texts, " ")
+}
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name     string
+		entries  string
+		maxItems int
+		maxDepth int
+		pages    []string
+	}{
+		{"no limit", numberedEntries(30), 0, 0, []string{numberedEntries(30)}},
+		{"fits", "1 2 3", 3, 0, []string{"1 2 3"}},
+		{"one more", "1 2 3 4", 3, 0, []string{"1 2", "3 4"}},
+		{"three pages", numberedEntries(7), 3, 0, []string{"1 2", "3 4", "5 6 7"}},
+		{"minimum of two", "1 2 3", 1, 0, []string{"1", "2", "3"}},
+		{"separator at split", "1 - 2 3", 3, 0, []string{"1", "2 3"}},
+		{"separator after split", "1 2 - 3", 3, 0, []string{"1 2", "3"}},
+		{"trailing separators", "1 2 - -", 3, 0, []string{"1 2"}},
+		{"separator kept inside", "1 - 2 3 4", 4, 0, []string{"1 - 2", "3 4"}},
+		{"within depth", numberedEntries(7), 3, 2, []string{"1 2", "3 4", "5 6 7"}},
+		{"too deep", numberedEntries(7), 3, 1, nil},
+		{"depth without split", numberedEntries(3), 3, 1, []string{"1 2 3"}},
+		{"last page full", numberedEntries(10), 4, 0, []string{"1 2 3", "4 5 6", "7 8 9 10"}},
+	}
+	for _, tt := range tests {
+		r := NewRegistry()
+		r.MaxItems, r.MaxDepth = tt.maxItems, tt.maxDepth
+		pages, ok := r.paginate(entries(tt.entries))
+		if ok != (tt.pages != nil) {
+			t.Errorf("%s: got ok %t, want %t", tt.name, ok, tt.pages != nil)
+			continue
+		}
+		var got []string
+		for _, page := range pages {
+			var texts []string
+			for _, c := range page {
+				if c.Text == "" {
+					texts = append(texts, "-")
+				} else {
+					texts = append(texts, c.Text)
+				}
+			}
+			got = append(got, strings.Join(texts, " "))
+		}
+		if !reflect.DeepEqual(got, tt.pages) {
+			t.Errorf("%s: got pages %q, want %q", tt.name, got, tt.pages)
+		}
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"Copy", "Copy"},
+		{"&Copy", "Copy"},
+		{"Save &As...", "Save As..."},
+		{"Copy\tCtrl+C", "Copy"},
+		{"&Copy\tCtrl+C", "Copy"},
+		{"Fish && Chips", "Fish & Chips"},
+		{"&Fish &&&Chips", "Fish &Chips"},
+		{"Tabs\tCtrl+T\tExtra", "Tabs"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := plainText(tt.text); got != tt.want {
+			t.Errorf("plainText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	r := NewRegistry()
+	r.Contribute("context/editor", Contribution{Text: "&Copy\tCtrl+C", ID: 1})
+	r.Contribute("context/editor", Contribution{Text: "Fish && Chips", ID: 2})
+	r.Contribute("context/editor", Contribution{Text: "Paste", ID: 3, Group: "b"})
+	r.Contribute("context/editor", Contribution{Text: "&Paste", ID: 4, Group: "a"})
+	r.Contribute("context/editor", Contribution{Text: "Undo", ID: 5, Order: 2})
+	r.Contribute("context/editor", Contribution{Text: "U&ndo", ID: 6, Order: 1})
+	r.Contribute("context", Contribution{Text: "editor/Copy", ID: 7})
+	r.Contribute("context", Contribution{Text: "Help", ID: 8})
+	tests := []struct {
+		path string
+		id   uint32
+		ok   bool
+	}{
+		{"context/editor/Copy", 1, true},
+		{"context/editor/&Copy", 0, false},
+		{"context/editor/Copy\tCtrl+C", 0, false},
+		{"context/editor/Fish & Chips", 2, true},
+		// Duplicate labels resolve to the first item in menu order.
+		{"context/editor/Paste", 4, true},
+		{"context/editor/Undo", 6, true},
+		// The longest matching location wins.
+		{"context/Help", 8, true},
+		{"context/editor/Help", 0, false},
+		{"context/editor", 0, false},
+		{"other/Copy", 0, false},
+	}
+	for _, tt := range tests {
+		c, ok := r.lookup(tt.path)
+		if ok != tt.ok || c.ID != tt.id {
+			t.Errorf("lookup(%q) = ID %d, ok %t, want ID %d, ok %t", tt.path, c.ID, ok, tt.id, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package menu
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kroppt/winmenu/internal/win"
+)
+
+var procGetGuiResources = win.User32.NewProc("GetGuiResources")
+
+const (
+	grGDIObjects  = 0
+	grUserObjects = 1
+)
+
+// DefaultObjectQuota is the default per-process limit of both GDI and USER
+// objects. Creating menus or bitmaps beyond it fails.
+const DefaultObjectQuota = 10000
+
+// GUIResources returns the numbers of GDI objects, such as bitmaps, and USER
+// objects, such as menus, the process is using.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-getguiresources)
+func GUIResources() (gdi, user int, ok bool) {
+	process, _, _ := procGetCurrentProcess.Call()
+	g, _, _ := procGetGuiResources.Call(process, grGDIObjects)
+	u, _, _ := procGetGuiResources.Call(process, grUserObjects)
+	// Both counts are zero only if the calls failed.
+	if g == 0 && u == 0 {
+		return 0, 0, false
+	}
+	return int(g), int(u), true
+}
+
+// ResourceMonitor periodically checks the GDI and USER object counts of the
+// process and warns when either approaches the quota, which icon-heavy dynamic
+// menus can exhaust by leaking bitmaps.
+type ResourceMonitor struct {
+	// How often the counts are checked. Defaults to one second.
+	Interval time.Duration
+	// The count of either kind at which OnWarning is called. Defaults to 90%
+	// of DefaultObjectQuota.
+	Threshold int
+	// Called on the monitor's goroutine when a count reaches Threshold, and
+	// again each time it rises further while still at or above Threshold.
+	OnWarning func(gdi, user int)
+	mu        sync.Mutex
+	stop      chan struct{}
+}
+
+// Start starts checking the counts on a new goroutine. It does nothing if the
+// monitor is already running.
+func (m *ResourceMonitor) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		return
+	}
+	m.stop = make(chan struct{})
+	go m.run(m.stop)
+}
+
+// Stop stops checking the counts.
+func (m *ResourceMonitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+func (m *ResourceMonitor) run(stop <-chan struct{}) {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	threshold := m.Threshold
+	if threshold <= 0 {
+		threshold = DefaultObjectQuota * 9 / 10
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastGDI, lastUser := 0, 0
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		gdi, user, ok := GUIResources()
+		if !ok {
+			continue
+		}
+		rising := gdi > lastGDI || user > lastUser
+		if rising && (gdi >= threshold || user >= threshold) && m.OnWarning != nil {
+			m.OnWarning(gdi, user)
+		}
+		lastGDI, lastUser = gdi, user
+	}
+}
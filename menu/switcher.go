@@ -0,0 +1,193 @@
+package menu
+
+import (
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/kroppt/winmenu"
+	"github.com/kroppt/winmenu/internal/win"
+)
+
+var (
+	procEnumWindows         = win.User32.NewProc("EnumWindows")
+	procIsWindowVisible     = win.User32.NewProc("IsWindowVisible")
+	procGetWindow           = win.User32.NewProc("GetWindow")
+	procGetWindowLongPtr    = longPtrProc("GetWindowLongPtrW", "GetWindowLongW")
+	procGetWindowText       = win.User32.NewProc("GetWindowTextW")
+	procGetWindowTextLength = win.User32.NewProc("GetWindowTextLengthW")
+	procSendMessageTimeout  = win.User32.NewProc("SendMessageTimeoutW")
+	procGetClassLongPtr     = longPtrProc("GetClassLongPtrW", "GetClassLongW")
+	procIsIconic            = win.User32.NewProc("IsIconic")
+	procShowWindow          = win.User32.NewProc("ShowWindow")
+	procSetForegroundWindow = win.User32.NewProc("SetForegroundWindow")
+
+	windowEnumMu       sync.Mutex
+	windowEnumHandles  []uintptr
+	windowEnumCallback = syscall.NewCallback(func(hwnd, lparam uintptr) uintptr {
+		windowEnumHandles = append(windowEnumHandles, hwnd)
+		return 1
+	})
+)
+
+// longPtrProc returns the named user32 function, or fallback if user32 does
+// not export it. 32-bit user32 only has the ...Long functions, and the ...LongPtr
+// names are macros for them there.
+func longPtrProc(name, fallback string) *win.Proc {
+	if proc := win.User32.NewProc(name); proc.Find() == nil {
+		return proc
+	}
+	return win.User32.NewProc(fallback)
+}
+
+const (
+	gwOwner          = 4
+	gwlExStyle       = ^uintptr(19) // -20
+	gclpHIconSm      = ^uintptr(33) // -34
+	wsExToolWindow   = 0x00000080
+	wmGetIcon        = 0x007F
+	iconSmall        = 0
+	iconSmall2       = 2
+	smtoAbortIfHung  = 0x0002
+	swRestore        = 9
+	iconQueryTimeout = 100
+)
+
+// Window describes a top-level window that appears in the taskbar.
+type Window struct {
+	// The handle of the window.
+	Handle winmenu.HWnd
+	// The window title.
+	Title string
+	// The small window icon, or zero if the window has none.
+	Icon winmenu.HIcon
+}
+
+// Windows returns the visible, unowned top-level windows other than tool
+// windows, in z-order.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-enumwindows)
+func Windows() (windows []Window, ok bool) {
+	windowEnumMu.Lock()
+	windowEnumHandles = nil
+	ret, _, _ := procEnumWindows.Call(windowEnumCallback, 0)
+	handles := windowEnumHandles
+	windowEnumHandles = nil
+	windowEnumMu.Unlock()
+	if ret == 0 {
+		return nil, false
+	}
+	for _, hwnd := range handles {
+		if visible, _, _ := procIsWindowVisible.Call(hwnd); visible == 0 {
+			continue
+		}
+		if owner, _, _ := procGetWindow.Call(hwnd, gwOwner); owner != 0 {
+			continue
+		}
+		exStyle, _, _ := procGetWindowLongPtr.Call(hwnd, gwlExStyle)
+		if exStyle&wsExToolWindow != 0 {
+			continue
+		}
+		n, _, _ := procGetWindowTextLength.Call(hwnd)
+		if n == 0 {
+			continue
+		}
+		buf := make([]uint16, n+1)
+		procGetWindowText.Call(hwnd, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+		windows = append(windows, Window{
+			Handle: winmenu.HWnd(hwnd),
+			Title:  syscall.UTF16ToString(buf),
+			Icon:   windowIcon(hwnd),
+		})
+	}
+	return windows, true
+}
+
+func windowIcon(hwnd uintptr) winmenu.HIcon {
+	for _, which := range []uintptr{iconSmall2, iconSmall} {
+		var icon uintptr
+		ret, _, _ := procSendMessageTimeout.Call(hwnd, wmGetIcon, which, 0, smtoAbortIfHung,
+			iconQueryTimeout, uintptr(unsafe.Pointer(&icon)))
+		if ret != 0 && icon != 0 {
+			return winmenu.HIcon(icon)
+		}
+	}
+	icon, _, _ := procGetClassLongPtr.Call(hwnd, gclpHIconSm)
+	return winmenu.HIcon(icon)
+}
+
+// Activate restores the window if it is minimized and brings it to the
+// foreground.
+func (w Window) Activate() (ok bool) {
+	if iconic, _, _ := procIsIconic.Call(uintptr(w.Handle)); iconic != 0 {
+		procShowWindow.Call(uintptr(w.Handle), swRestore)
+	}
+	ret, _, _ := procSetForegroundWindow.Call(uintptr(w.Handle))
+	return ret != 0
+}
+
+// WindowMenu is a popup menu listing the open top-level windows with their
+// icons and titles. Choosing an entry activates the window.
+type WindowMenu struct {
+	// The popup menu, ready to be attached with SetSubMenu.
+	Menu winmenu.HMenu
+	// The listed windows, in item order.
+	Windows []Window
+	bitmaps []winmenu.HBitmap
+	firstID uint32
+}
+
+// NewWindowMenu creates a WindowMenu whose items use the command IDs firstID
+// through firstID+len(Windows)-1. The window hwnd, typically the caller's own,
+// is left out of the list; it may be zero.
+func NewWindowMenu(hwnd winmenu.HWnd, firstID uint32) (wm *WindowMenu, ok bool) {
+	windows, ok := Windows()
+	if !ok {
+		return nil, false
+	}
+	hmenu, ok := winmenu.CreatePopupMenu()
+	if !ok {
+		return nil, false
+	}
+	wm = &WindowMenu{Menu: hmenu, firstID: firstID}
+	size, _, _ := procGetSystemMetrics.Call(smCXSmIcon)
+	for _, w := range windows {
+		if w.Handle == hwnd {
+			continue
+		}
+		mii := winmenu.NewMenuItemInfo()
+		mii.SetAsString(itemLabel(w.Title, 60))
+		mii.SetID(firstID + uint32(len(wm.Windows)))
+		if w.Icon != 0 {
+			if hbm, ok := BitmapFromIcon(w.Icon, int(size)); ok {
+				wm.bitmaps = append(wm.bitmaps, hbm)
+				mii.SetItemBitmap(hbm)
+			}
+		}
+		if !hmenu.InsertMenuItem(uint32(len(wm.Windows)), true, mii) {
+			wm.Release()
+			hmenu.Destroy()
+			return nil, false
+		}
+		wm.Windows = append(wm.Windows, w)
+	}
+	return wm, true
+}
+
+// Select handles a WM_COMMAND ID, activating the chosen window if the ID
+// belongs to one of the menu's items. It reports whether the ID was handled.
+func (wm *WindowMenu) Select(id uint32) bool {
+	if id < wm.firstID || id-wm.firstID >= uint32(len(wm.Windows)) {
+		return false
+	}
+	wm.Windows[id-wm.firstID].Activate()
+	return true
+}
+
+// Release deletes the icon bitmaps of the menu items. Call it once the menu is
+// no longer displayed.
+func (wm *WindowMenu) Release() {
+	for _, hbm := range wm.bitmaps {
+		hbm.Delete()
+	}
+	wm.bitmaps = nil
+}
@@ -0,0 +1,109 @@
+package menu
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/kroppt/winmenu"
+)
+
+const (
+	menuExVersion    = 1
+	menuExOffset     = 4
+	menuExPopup      = 0x01
+	menuExLastItem   = 0x80
+	menuExHeaderSize = 8
+)
+
+// TemplateItem is an item of a menu built from a template.
+type TemplateItem struct {
+	// The item text. It is ignored for separators.
+	Text string
+	// The command ID sent with WM_COMMAND when the item is chosen.
+	ID uint32
+	// The item type, such as MFT_SEPARATOR or MFT_RADIOCHECK.
+	Type winmenu.TypeFlag
+	// The item state, such as MFS_CHECKED or MFS_GRAYED.
+	State winmenu.StateFlag
+	// The items of the submenu the item opens, if any.
+	Items []TemplateItem
+}
+
+// MenuTemplate serializes items and their submenus into a binary MENUEX
+// template for LoadMenuIndirect.
+// (https://docs.microsoft.com/en-us/windows/desktop/menurc/menuex-template-header)
+func MenuTemplate(items []TemplateItem) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint16(menuExVersion))
+	binary.Write(&buf, binary.LittleEndian, uint16(menuExOffset))
+	binary.Write(&buf, binary.LittleEndian, uint32(0)) // help ID
+	writeTemplateItems(&buf, items)
+	return buf.Bytes()
+}
+
+// writeTemplateItems writes MENUEX_TEMPLATE_ITEM structures, each aligned to a
+// DWORD boundary and followed by its submenu, if any.
+// (https://docs.microsoft.com/en-us/windows/desktop/menurc/menuex-template-item)
+func writeTemplateItems(buf *bytes.Buffer, items []TemplateItem) {
+	for i, item := range items {
+		var flags uint16
+		if len(item.Items) > 0 {
+			flags |= menuExPopup
+		}
+		if i == len(items)-1 {
+			flags |= menuExLastItem
+		}
+		text := item.Text
+		if item.Type&winmenu.MFT_SEPARATOR != 0 {
+			text = ""
+		}
+		if j := strings.IndexByte(text, 0); j >= 0 {
+			text = text[:j]
+		}
+		binary.Write(buf, binary.LittleEndian, uint32(item.Type))
+		binary.Write(buf, binary.LittleEndian, uint32(item.State))
+		binary.Write(buf, binary.LittleEndian, item.ID)
+		binary.Write(buf, binary.LittleEndian, flags)
+		binary.Write(buf, binary.LittleEndian, utf16.Encode([]rune(text+"\x00")))
+		alignTemplate(buf)
+		if len(item.Items) > 0 {
+			binary.Write(buf, binary.LittleEndian, uint32(0)) // help ID
+			writeTemplateItems(buf, item.Items)
+		}
+	}
+}
+
+// alignTemplate pads buf to a DWORD boundary.
+func alignTemplate(buf *bytes.Buffer) {
+	for buf.Len()%4 != 0 {
+		buf.WriteByte(0)
+	}
+}
+
+// BuildMenu creates a menu bar holding items and their submenus with a single
+// LoadMenuIndirect call, which is much faster for large menus than inserting
+// the items one by one.
+func BuildMenu(items []TemplateItem) (hmenu winmenu.HMenu, ok bool) {
+	return winmenu.LoadMenuIndirect(MenuTemplate(items))
+}
+
+// BuildPopupMenu is like BuildMenu, but creates a popup menu, which can be
+// shown with TrackPopup or attached as a submenu. items must not be empty.
+func BuildPopupMenu(items []TemplateItem) (hmenu winmenu.HMenu, ok bool) {
+	// Load the items as the submenu of a single menu bar item, then detach it.
+	bar, ok := BuildMenu([]TemplateItem{{Text: "popup", Items: items}})
+	if !ok {
+		return 0, false
+	}
+	defer bar.Destroy()
+	hmenu, ok = bar.SubMenu(0)
+	if !ok {
+		return 0, false
+	}
+	if !bar.RemoveMenu(0, true) {
+		return 0, false
+	}
+	return hmenu, true
+}
@@ -0,0 +1,80 @@
+package menu
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kroppt/winmenu"
+)
+
+func TestMenuTemplate(t *testing.T) {
+	items := []TemplateItem{
+		{Text: "&File", Items: []TemplateItem{
+			{Text: "Open", ID: 1, State: winmenu.MFS_CHECKED},
+			{Text: "ignored", Type: winmenu.MFT_SEPARATOR},
+			{Text: "Recent", Items: []TemplateItem{
+				{Text: "a\x00b", ID: 2, Type: winmenu.MFT_RADIOCHECK},
+			}},
+		}},
+		{Text: "Ab", ID: 3, State: winmenu.MFS_GRAYED},
+	}
+	want := []byte{
+		// Header: version 1, offset 4, help ID.
+		0x01, 0x00, 0x04, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		// "&File": type, state, ID, popup flag, text, padding, help ID.
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x01, 0x00,
+		'&', 0x00, 'F', 0x00, 'i', 0x00, 'l', 0x00, 'e', 0x00, 0x00, 0x00,
+		0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		// "Open": checked, ID 1, no flags; ends on a DWORD boundary.
+		0x00, 0x00, 0x00, 0x00,
+		0x08, 0x00, 0x00, 0x00,
+		0x01, 0x00, 0x00, 0x00,
+		0x00, 0x00,
+		'O', 0x00, 'p', 0x00, 'e', 0x00, 'n', 0x00, 0x00, 0x00,
+		// Separator: its text is dropped.
+		0x00, 0x08, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00,
+		0x00, 0x00,
+		// "Recent": last item and popup, then the submenu help ID.
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x81, 0x00,
+		'R', 0x00, 'e', 0x00, 'c', 0x00, 'e', 0x00, 'n', 0x00, 't', 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		// "a": radio check, ID 2, last item, text cut at the NUL, padding.
+		0x00, 0x02, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x02, 0x00, 0x00, 0x00,
+		0x80, 0x00,
+		'a', 0x00, 0x00, 0x00,
+		0x00, 0x00,
+		// "Ab": grayed, ID 3, last item.
+		0x00, 0x00, 0x00, 0x00,
+		0x03, 0x00, 0x00, 0x00,
+		0x03, 0x00, 0x00, 0x00,
+		0x80, 0x00,
+		'A', 0x00, 'b', 0x00, 0x00, 0x00,
+	}
+	got := MenuTemplate(items)
+	if !bytes.Equal(got, want) {
+		t.Errorf("MenuTemplate =\n% x\nwant\n% x", got, want)
+	}
+	if len(got)%4 != 0 {
+		t.Errorf("template length %d is not DWORD aligned", len(got))
+	}
+}
+
+func TestMenuTemplateEmpty(t *testing.T) {
+	want := []byte{0x01, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00}
+	if got := MenuTemplate(nil); !bytes.Equal(got, want) {
+		t.Errorf("MenuTemplate(nil) = % x, want % x", got, want)
+	}
+}
@@ -0,0 +1,128 @@
+package menu
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/kroppt/winmenu"
+)
+
+// Session is a record of one menu session in a Transcript.
+type Session struct {
+	// When the menu was shown and closed.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// The item texts of the menu shown, in order. Separators are empty.
+	Items []string `json:"items"`
+	// The IDs of the items highlighted, in order.
+	Highlighted []uint32 `json:"highlighted,omitempty"`
+	// The ID of the chosen item, or zero if the menu was dismissed.
+	Selected uint32 `json:"selected,omitempty"`
+}
+
+// Transcript keeps a record of the last menu sessions, for including in
+// support bundles when users report that a menu did the wrong thing. The
+// application reports the session events, typically from its window
+// procedure. It is safe to use from multiple goroutines.
+type Transcript struct {
+	max      int
+	mu       sync.Mutex
+	sessions []Session
+	current  *Session
+}
+
+// NewTranscript returns a pointer to a new Transcript keeping the last max
+// sessions.
+func NewTranscript(max int) *Transcript {
+	if max < 1 {
+		max = 1
+	}
+	return &Transcript{max: max}
+}
+
+// Begin starts recording a session for hmenu, ending any session in progress.
+func (t *Transcript) Begin(hmenu winmenu.HMenu) {
+	var items []string
+	n, _, _ := procGetMenuItemCount.Call(uintptr(hmenu))
+	for pos := uint32(0); int32(n) > 0 && pos < uint32(n); pos++ {
+		text, _ := hmenu.ItemString(pos, true)
+		items = append(items, text)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.end()
+	t.current = &Session{Start: time.Now(), Items: items}
+}
+
+// Highlight records that the item with the given ID was highlighted, as
+// reported by WM_MENUSELECT.
+func (t *Transcript) Highlight(id uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current != nil {
+		t.current.Highlighted = append(t.current.Highlighted, id)
+	}
+}
+
+// Select records that the item with the given ID was chosen, as reported by
+// WM_COMMAND or returned by TrackPopup. WM_COMMAND can arrive after the session
+// has ended, so without a session in progress the choice is recorded in the
+// last session.
+func (t *Transcript) Select(id uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case t.current != nil:
+		t.current.Selected = id
+	case len(t.sessions) > 0:
+		t.sessions[len(t.sessions)-1].Selected = id
+	}
+}
+
+// End ends the session in progress, for example on WM_EXITMENULOOP.
+func (t *Transcript) End() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.end()
+}
+
+func (t *Transcript) end() {
+	if t.current == nil {
+		return
+	}
+	t.current.End = time.Now()
+	t.sessions = append(t.sessions, *t.current)
+	if len(t.sessions) > t.max {
+		t.sessions = t.sessions[len(t.sessions)-t.max:]
+	}
+	t.current = nil
+}
+
+// Track shows hmenu with TrackPopup and records the session. The arguments and
+// results are those of TrackPopup. Highlights are still reported by the
+// application.
+func (t *Transcript) Track(hmenu winmenu.HMenu, flags winmenu.TrackFlag, x, y int32, hwnd winmenu.HWnd) (cmd uint32, ok bool) {
+	t.Begin(hmenu)
+	cmd, ok = hmenu.TrackPopup(flags, x, y, hwnd)
+	if cmd != 0 {
+		t.Select(cmd)
+	}
+	t.End()
+	return cmd, ok
+}
+
+// Sessions returns the recorded sessions, oldest first.
+func (t *Transcript) Sessions() []Session {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Session(nil), t.sessions...)
+}
+
+// WriteJSON writes the recorded sessions to w as a JSON array.
+func (t *Transcript) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t.Sessions())
+}
@@ -0,0 +1,97 @@
+package menu
+
+import (
+	"time"
+	"unsafe"
+
+	"github.com/kroppt/winmenu"
+	"github.com/kroppt/winmenu/internal/win"
+)
+
+var (
+	procGetLastInputInfo = win.User32.NewProc("GetLastInputInfo")
+	procGetTickCount     = win.Kernel32.NewProc("GetTickCount")
+)
+
+const wmCancelMode = 0x001F
+
+type lastInputInfo struct {
+	cbSize uint32
+	dwTime uint32
+}
+
+// Watchdog shows popup menus and watches for ones left open without user
+// input, as can happen on unattended kiosks, where an open menu's modal loop
+// keeps the application from doing anything else.
+type Watchdog struct {
+	// How long a popup may stay open without keyboard or mouse input. Zero,
+	// the default, or less disables the watchdog, so that Track only shows
+	// the popup.
+	Idle time.Duration
+	// Whether a popup that has been idle too long is dismissed, as if the user
+	// had clicked elsewhere.
+	Dismiss bool
+	// If not nil, called on a separate goroutine when a popup has been idle
+	// too long.
+	OnIdle func(hmenu winmenu.HMenu)
+}
+
+// Track shows hmenu with TrackPopup while watching it. The arguments and
+// results are those of TrackPopup; a dismissed menu returns no selection.
+func (wd *Watchdog) Track(hmenu winmenu.HMenu, flags winmenu.TrackFlag, x, y int32, hwnd winmenu.HWnd) (cmd uint32, ok bool) {
+	if wd.Idle <= 0 {
+		return hmenu.TrackPopup(flags, x, y, hwnd)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go wd.watch(hmenu, hwnd, done)
+	return hmenu.TrackPopup(flags, x, y, hwnd)
+}
+
+func (wd *Watchdog) watch(hmenu winmenu.HMenu, hwnd winmenu.HWnd, done <-chan struct{}) {
+	interval := wd.Idle / 4
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	opened := time.Now()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		idle := time.Since(opened)
+		if d, ok := idleTime(); ok && d < idle {
+			idle = d
+		}
+		if idle < wd.Idle {
+			continue
+		}
+		if wd.OnIdle != nil {
+			go wd.OnIdle(hmenu)
+		}
+		if wd.Dismiss {
+			// The menu loop passes WM_CANCELMODE to the owner window, whose
+			// default handling ends the menu.
+			procPostMessage.Call(uintptr(hwnd), wmCancelMode, 0, 0)
+		}
+		return
+	}
+}
+
+// idleTime returns how long ago the last keyboard or mouse input of the
+// session was received.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-getlastinputinfo)
+func idleTime() (d time.Duration, ok bool) {
+	lii := lastInputInfo{}
+	lii.cbSize = uint32(unsafe.Sizeof(lii))
+	ret, _, _ := procGetLastInputInfo.Call(uintptr(unsafe.Pointer(&lii)))
+	if ret == 0 {
+		return 0, false
+	}
+	now, _, _ := procGetTickCount.Call()
+	// The subtraction wraps correctly when the tick count overflows.
+	return time.Duration(uint32(now)-lii.dwTime) * time.Millisecond, true
+}
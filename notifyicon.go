@@ -0,0 +1,32 @@
+package winmenu
+
+import (
+	"unsafe"
+
+	"github.com/kroppt/winmenu/internal/win"
+)
+
+var (
+	modshell32                 = win.Shell32
+	procShellNotifyIconGetRect = modshell32.NewProc("Shell_NotifyIconGetRect")
+)
+
+type notifyIconIdentifier struct {
+	cbSize   uint32
+	hWnd     HWnd
+	uID      uint32
+	guidItem [16]byte
+}
+
+// NotifyIconRect returns the screen rectangle of the notification area icon
+// added by hwnd with the given ID. Tray context menus can be anchored to it,
+// for example centered horizontally on the icon and aligned above its top
+// edge, instead of being shown at the cursor.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/shellapi/nf-shellapi-shell_notifyicongetrect)
+func NotifyIconRect(hwnd HWnd, id uint32) (rc Rect, ok bool) {
+	nii := notifyIconIdentifier{hWnd: hwnd, uID: id}
+	nii.cbSize = uint32(unsafe.Sizeof(nii))
+	ret, _, _ := procShellNotifyIconGetRect.Call(uintptr(unsafe.Pointer(&nii)), uintptr(unsafe.Pointer(&rc)))
+	// The function returns an HRESULT.
+	return rc, ret == 0
+}
@@ -0,0 +1,66 @@
+package winmenu
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/kroppt/winmenu/internal/win"
+)
+
+var (
+	modkernel32          = win.Kernel32
+	procGetModuleHandle  = modkernel32.NewProc("GetModuleHandleW")
+	procLoadMenu         = moduser32.NewProc("LoadMenuW")
+	procLoadMenuIndirect = moduser32.NewProc("LoadMenuIndirectW")
+)
+
+// HInstance is a handle to a module, such as the executable or a DLL, whose
+// resources menus can be loaded from.
+// (https://docs.microsoft.com/en-us/windows/desktop/WinProg/windows-data-types#HINSTANCE)
+type HInstance uintptr
+
+// LoadMenu loads the menu resource with the given name from the module hinst,
+// or from the executable if hinst is zero. A name of the form "#123" refers to
+// the resource with the integer ID 123, as in resource scripts. The menu is not
+// destroyed automatically unless it is assigned to a window.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-loadmenuw)
+func LoadMenu(hinst HInstance, name string) (hmenu HMenu, ok bool) {
+	str, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return 0, false
+	}
+	return loadMenu(hinst, uintptr(unsafe.Pointer(str)))
+}
+
+// LoadMenuID is like LoadMenu, but takes the integer ID of the resource.
+func LoadMenuID(hinst HInstance, id uint16) (hmenu HMenu, ok bool) {
+	// MAKEINTRESOURCE passes the ID in place of the name pointer.
+	return loadMenu(hinst, uintptr(id))
+}
+
+func loadMenu(hinst HInstance, name uintptr) (hmenu HMenu, ok bool) {
+	if hinst == 0 {
+		ret, _, _ := procGetModuleHandle.Call(0)
+		hinst = HInstance(ret)
+	}
+	ret, _, err := procLoadMenu.Call(uintptr(hinst), name)
+	if !check("LoadMenu", ret != 0, err) {
+		return 0, false
+	}
+	return HMenu(ret), true
+}
+
+// LoadMenuIndirect creates a menu from a MENU or MENUEX template in memory,
+// creating all items and submenus in one call. The menu is of the same kind as
+// one created by CreateMenu.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-loadmenuindirectw)
+func LoadMenuIndirect(template []byte) (hmenu HMenu, ok bool) {
+	if len(template) == 0 {
+		return 0, false
+	}
+	ret, _, err := procLoadMenuIndirect.Call(uintptr(unsafe.Pointer(&template[0])))
+	if !check("LoadMenuIndirect", ret != 0, err) {
+		return 0, false
+	}
+	return HMenu(ret), true
+}
@@ -0,0 +1,58 @@
+package winmenu
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"github.com/kroppt/winmenu/internal/win"
+)
+
+// StrictMode selects how the wrappers that report failure with an ok bool
+// surface those failures in addition to returning false.
+type StrictMode int32
+
+// The strict modes.
+const (
+	// Failures are only reported through the ok result. This is the default.
+	StrictOff StrictMode = iota
+	// Failures are also logged with the standard log package, together with
+	// the Windows error code.
+	StrictLog
+	// Failures panic with the name of the Windows function and the error code.
+	StrictPanic
+)
+
+var strictMode int32
+
+func init() {
+	// Report procedures missing from the system DLLs like other failures.
+	win.Check = check
+}
+
+// errNoItem is reported for functions that signal a missing item without
+// setting the last error.
+var errNoItem = errors.New("menu item does not exist")
+
+// SetStrictMode changes how failures of the ok bool wrappers are surfaced. It is
+// meant for debug builds, to find call sites that ignore the ok result. It is
+// safe to call from multiple goroutines.
+func SetStrictMode(mode StrictMode) {
+	atomic.StoreInt32(&strictMode, int32(mode))
+}
+
+// check returns ok, first logging or panicking about a failed call of the named
+// Windows function according to the strict mode.
+func check(api string, ok bool, err error) bool {
+	if ok {
+		return true
+	}
+	switch StrictMode(atomic.LoadInt32(&strictMode)) {
+	case StrictLog:
+		log.Printf("winmenu: %s failed: %v", api, err)
+	case StrictPanic:
+		panic(fmt.Sprintf("winmenu: %s failed: %v", api, err))
+	}
+	return false
+}
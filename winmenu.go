@@ -1,19 +1,102 @@
+// Package winmenu is a thin wrapper around the Win32 menu API. Builders and
+// ready-made menu components live in the menu subpackage.
+//
+// Handles from other libraries, such as walk, lxn/win, or cgo code, can be
+// wrapped with the FromRaw functions and unwrapped with the Raw methods.
+// Neither conversion transfers ownership: a handle is still released by the
+// code that created it, and wrapping a handle does not make this package
+// destroy or delete it. Only call Destroy or Delete on handles you own.
 package winmenu
 
 import (
+	"strings"
 	"syscall"
+	"unicode/utf16"
 	"unsafe"
+
+	"github.com/kroppt/winmenu/internal/win"
 )
 
 var (
-	moduser32           = syscall.NewLazyDLL("user32.dll")
-	procCreateMenu      = moduser32.NewProc("CreateMenu")
-	procInsertMenuItem  = moduser32.NewProc("InsertMenuItemW")
-	procGetMenu         = moduser32.NewProc("GetMenu")
-	procSetMenu         = moduser32.NewProc("SetMenu")
-	procCreatePopupMenu = moduser32.NewProc("CreatePopupMenu")
+	moduser32                        = win.User32
+	procCreateMenu                   = moduser32.NewProc("CreateMenu")
+	procInsertMenuItem               = moduser32.NewProc("InsertMenuItemW")
+	procGetMenu                      = moduser32.NewProc("GetMenu")
+	procSetMenu                      = moduser32.NewProc("SetMenu")
+	procCreatePopupMenu              = moduser32.NewProc("CreatePopupMenu")
+	procSetMenuItemInfo              = moduser32.NewProc("SetMenuItemInfoW")
+	procDestroyMenu                  = moduser32.NewProc("DestroyMenu")
+	procGetMenuItemRect              = moduser32.NewProc("GetMenuItemRect")
+	procTrackPopupMenu               = moduser32.NewProc("TrackPopupMenu")
+	procTrackPopupMenuEx             = moduser32.NewProc("TrackPopupMenuEx")
+	procAppendMenu                   = moduser32.NewProc("AppendMenuW")
+	procDeleteMenu                   = moduser32.NewProc("DeleteMenu")
+	procRemoveMenu                   = moduser32.NewProc("RemoveMenu")
+	procGetMenuItemInfo              = moduser32.NewProc("GetMenuItemInfoW")
+	procGetMenuItemID                = moduser32.NewProc("GetMenuItemID")
+	procGetSubMenu                   = moduser32.NewProc("GetSubMenu")
+	procDrawMenuBar                  = moduser32.NewProc("DrawMenuBar")
+	procEnableMenuItem               = moduser32.NewProc("EnableMenuItem")
+	procCheckMenuItem                = moduser32.NewProc("CheckMenuItem")
+	procHiliteMenuItem               = moduser32.NewProc("HiliteMenuItem")
+	procGetMenuState                 = moduser32.NewProc("GetMenuState")
+	procGetMenuString                = moduser32.NewProc("GetMenuStringW")
+	procSetMenuDefaultItem           = moduser32.NewProc("SetMenuDefaultItem")
+	procGetMenuDefaultItem           = moduser32.NewProc("GetMenuDefaultItem")
+	procSetMenuItemBitmaps           = moduser32.NewProc("SetMenuItemBitmaps")
+	procGetMenuCheckMarkDimensions   = moduser32.NewProc("GetMenuCheckMarkDimensions")
+	procGetMenuBarInfo               = moduser32.NewProc("GetMenuBarInfo")
+	procMenuItemFromPoint            = moduser32.NewProc("MenuItemFromPoint")
+	procSetMenuContextHelpId         = moduser32.NewProc("SetMenuContextHelpId")
+	procGetMenuContextHelpId         = moduser32.NewProc("GetMenuContextHelpId")
+	procCalculatePopupWindowPosition = moduser32.NewProc("CalculatePopupWindowPosition")
 )
 
+// Proc is a procedure of a system DLL, returned by User32Proc. It has the
+// methods of syscall.LazyProc, except that its Call does not panic if the DLL
+// does not export the procedure. It fails according to the strict mode instead
+// and returns the error from Find.
+type Proc = win.Proc
+
+// User32Proc returns the named procedure of user32.dll, loaded through the same
+// lazy DLL as the package's own wrappers. Use it to call menu functions the
+// package does not wrap.
+func User32Proc(name string) *Proc {
+	return moduser32.NewProc(name)
+}
+
+// HWnd is a handle to a window.
+// (https://docs.microsoft.com/en-us/windows/desktop/WinProg/windows-data-types#HWND)
+type HWnd uintptr
+
+// Point defines the x- and y-coordinates of a point.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/windef/ns-windef-point)
+type Point struct {
+	X, Y int32
+}
+
+// args returns pt as the arguments of a function that takes a POINT by value,
+// which is one register on 64-bit Windows and two stack slots on 32-bit.
+func (pt Point) args() []uintptr {
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		return []uintptr{uintptr(uint32(pt.X)) | uintptr(uint32(pt.Y))<<32}
+	}
+	return []uintptr{uintptr(pt.X), uintptr(pt.Y)}
+}
+
+// Size defines the width and height of a rectangle.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/windef/ns-windef-size)
+type Size struct {
+	CX, CY int32
+}
+
+// Rect defines a rectangle by the coordinates of its upper-left and lower-right
+// corners.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/windef/ns-windef-rect)
+type Rect struct {
+	Left, Top, Right, Bottom int32
+}
+
 // HMenu is a handle to a menu.
 // (https://docs.microsoft.com/en-us/windows/desktop/WinProg/windows-data-types#HMENU)
 type HMenu uintptr
@@ -164,6 +247,150 @@ const (
 	HBMMENU_SYSTEM HBitmap = 1
 )
 
+// WM_COMMAND is sent to the owner window when the user selects a command item
+// from a menu. The low-order word of wParam is the menu item identifier.
+const WM_COMMAND = 0x0111
+
+// WM_MENUSELECT is sent to the owner window when the user highlights a menu
+// item. The low word of wParam is the command ID or position of the item.
+const WM_MENUSELECT = 0x011F
+
+// WM_EXITMENULOOP is sent to the owner window when a menu modal loop has
+// exited.
+const WM_EXITMENULOOP = 0x0212
+
+// WM_CLIPBOARDUPDATE is sent to clipboard format listeners when the contents
+// of the clipboard have changed.
+const WM_CLIPBOARDUPDATE = 0x031D
+
+// MenuFlag is a flag of the AppendMenu family of functions.
+type MenuFlag uint32
+
+// Controls the appearance and behavior of the new menu item. This parameter
+// can be a combination of the following values.
+const (
+	// Uses a bitmap as the menu item.
+	MF_BITMAP MenuFlag = 0x00000004
+	// Places a check mark next to the menu item.
+	MF_CHECKED MenuFlag = 0x00000008
+	// Disables the menu item so that it cannot be selected, but the flag does
+	// not gray it.
+	MF_DISABLED MenuFlag = 0x00000002
+	// Enables the menu item so that it can be selected, and restores it from
+	// its grayed state.
+	MF_ENABLED MenuFlag = 0x00000000
+	// Disables the menu item and grays it so that it cannot be selected.
+	MF_GRAYED MenuFlag = 0x00000001
+	// Functions the same as the MF_MENUBREAK flag for a menu bar. For a
+	// drop-down menu, submenu, or shortcut menu, the new column is separated
+	// from the old column by a vertical line.
+	MF_MENUBARBREAK MenuFlag = 0x00000020
+	// Places the item on a new line (for a menu bar) or in a new column (for a
+	// drop-down menu, submenu, or shortcut menu) without separating columns.
+	MF_MENUBREAK MenuFlag = 0x00000040
+	// Specifies that the item is an owner-drawn item.
+	MF_OWNERDRAW MenuFlag = 0x00000100
+	// Specifies that the menu item opens a drop-down menu or submenu. The id
+	// parameter specifies a handle to the drop-down menu or submenu.
+	MF_POPUP MenuFlag = 0x00000010
+	// Draws a horizontal dividing line. This flag is used only in a drop-down
+	// menu, submenu, or shortcut menu. The line cannot be grayed, disabled, or
+	// highlighted. The text and id parameters are ignored.
+	MF_SEPARATOR MenuFlag = 0x00000800
+	// Specifies that the menu item is a text string.
+	MF_STRING MenuFlag = 0x00000000
+	// Does not place a check mark next to the item (default).
+	MF_UNCHECKED MenuFlag = 0x00000000
+	// Indicates that the item parameter gives the identifier of the menu
+	// item. This is the default if neither flag is specified.
+	MF_BYCOMMAND MenuFlag = 0x00000000
+	// Indicates that the item parameter gives the zero-based relative
+	// position of the menu item.
+	MF_BYPOSITION MenuFlag = 0x00000400
+	// Highlights the menu bar item, for HiliteMenuItem.
+	MF_HILITE MenuFlag = 0x00000080
+	// Removes the highlight from the menu bar item, for HiliteMenuItem
+	// (default).
+	MF_UNHILITE MenuFlag = 0x00000000
+	// The item is the default item of the menu, as reported by State.
+	MF_DEFAULT MenuFlag = 0x00001000
+)
+
+// TrackFlag is a TrackPopupMenu flag.
+type TrackFlag uint32
+
+// Use one of the following flags to specify how the function positions the
+// shortcut menu horizontally, vertically, and which mouse button it tracks.
+const (
+	// Centers the shortcut menu horizontally relative to the coordinate
+	// specified by the x parameter.
+	TPM_CENTERALIGN TrackFlag = 0x0004
+	// Positions the shortcut menu so that its left side is aligned with the
+	// coordinate specified by the x parameter.
+	TPM_LEFTALIGN TrackFlag = 0x0000
+	// Positions the shortcut menu so that its right side is aligned with the
+	// coordinate specified by the x parameter.
+	TPM_RIGHTALIGN TrackFlag = 0x0008
+	// Positions the shortcut menu so that its bottom side is aligned with the
+	// coordinate specified by the y parameter.
+	TPM_BOTTOMALIGN TrackFlag = 0x0020
+	// Positions the shortcut menu so that its top side is aligned with the
+	// coordinate specified by the y parameter.
+	TPM_TOPALIGN TrackFlag = 0x0000
+	// Centers the shortcut menu vertically relative to the coordinate
+	// specified by the y parameter.
+	TPM_VCENTERALIGN TrackFlag = 0x0010
+	// The user can select menu items with only the left mouse button.
+	TPM_LEFTBUTTON TrackFlag = 0x0000
+	// The user can select menu items with both the left and right mouse
+	// buttons.
+	TPM_RIGHTBUTTON TrackFlag = 0x0002
+	// If the menu cannot be shown at the specified location without
+	// overlapping the excluded rectangle, the system tries to accommodate the
+	// requested horizontal alignment before the requested vertical alignment.
+	TPM_HORIZONTAL TrackFlag = 0x0000
+	// If the menu cannot be shown at the specified location without
+	// overlapping the excluded rectangle, the system tries to accommodate the
+	// requested vertical alignment before the requested horizontal alignment.
+	TPM_VERTICAL TrackFlag = 0x0040
+	// The function does not send notification messages when the user clicks
+	// a menu item.
+	TPM_NONOTIFY TrackFlag = 0x0080
+	// The function returns the menu item identifier of the user's selection
+	// in the return value.
+	TPM_RETURNCMD TrackFlag = 0x0100
+	// Use this flag to display a menu when another menu is already displayed.
+	// This is intended to support context menus within a menu.
+	TPM_RECURSE TrackFlag = 0x0001
+	// Animates the menu from left to right.
+	TPM_HORPOSANIMATION TrackFlag = 0x0400
+	// Animates the menu from right to left.
+	TPM_HORNEGANIMATION TrackFlag = 0x0800
+	// Animates the menu from top to bottom.
+	TPM_VERPOSANIMATION TrackFlag = 0x1000
+	// Animates the menu from bottom to top.
+	TPM_VERNEGANIMATION TrackFlag = 0x2000
+	// Displays the menu without animation.
+	TPM_NOANIMATION TrackFlag = 0x4000
+	// For right-to-left text layout, use TPM_LAYOUTRTL. By default, the text
+	// layout is left-to-right.
+	TPM_LAYOUTRTL TrackFlag = 0x8000
+	// Restricts the pop-up window to within the work area.
+	TPM_WORKAREA TrackFlag = 0x10000
+)
+
+// DefaultItemFlag is a GetMenuDefaultItem flag.
+type DefaultItemFlag uint32
+
+// Controls how DefaultItem searches for the default item.
+const (
+	// Returns the default item even if it is disabled.
+	GMDI_USEDISABLED DefaultItemFlag = 0x0001
+	// If the default item opens a submenu, searches the submenu for its
+	// default item, and so on, returning the innermost one.
+	GMDI_GOINTOPOPUPS DefaultItemFlag = 0x0002
+)
+
 // MenuItemInfo contains information about a menu item.
 //
 // Remarks:
@@ -244,17 +471,97 @@ func NewMenuItemInfo() *MenuItemInfo {
 	return new(MenuItemInfo)
 }
 
+// SetMask adds the given flags to the mask. Use it to select the members that
+// GetMenuItemInfo retrieves.
+func (mii *MenuItemInfo) SetMask(fmask MaskFlag) {
+	mii.fMask |= fmask
+}
+
+// Mask returns the mask flags.
+func (mii *MenuItemInfo) Mask() MaskFlag {
+	return mii.fMask
+}
+
+// Type returns the type flags.
+func (mii *MenuItemInfo) Type() TypeFlag {
+	return mii.fType
+}
+
+// State returns the state flags.
+func (mii *MenuItemInfo) State() StateFlag {
+	return mii.fState
+}
+
+// ID returns the item ID.
+func (mii *MenuItemInfo) ID() uint32 {
+	return mii.wID
+}
+
+// SubMenu returns the submenu handle, or zero if the item does not open a
+// submenu.
+func (mii *MenuItemInfo) SubMenu() HMenu {
+	return mii.hSubMenu
+}
+
+// Checkmark returns the checked bitmap handle.
+func (mii *MenuItemInfo) Checkmark() HBitmap {
+	return mii.hbmpChecked
+}
+
+// Uncheckmark returns the unchecked bitmap handle.
+func (mii *MenuItemInfo) Uncheckmark() HBitmap {
+	return mii.hbmpUnchecked
+}
+
+// ItemData returns the item data pointer.
+func (mii *MenuItemInfo) ItemData() *uint64 {
+	return mii.dwItemData
+}
+
+// Bitmap returns the item bitmap handle.
+func (mii *MenuItemInfo) Bitmap() HBitmap {
+	return mii.hbmpItem
+}
+
+// Text returns the item text, or an empty string if the item is not a text
+// item or the text was not retrieved.
+func (mii *MenuItemInfo) Text() string {
+	if mii.dwTypeData == nil || !mii.isString() {
+		return ""
+	}
+	return syscall.UTF16ToString(unsafe.Slice(mii.dwTypeData, mii.cch))
+}
+
+func (mii *MenuItemInfo) isString() bool {
+	return mii.fType&(MFT_BITMAP|MFT_SEPARATOR|MFT_OWNERDRAW) == 0
+}
+
 // SetAsString sets the masks and sets the string field to the given string.
+// The string is truncated at the first NUL character, and invalid UTF-8 is
+// replaced with U+FFFD; any other text reads back unchanged.
 func (mii *MenuItemInfo) SetAsString(str string) (ok bool) {
 	if mii.fType&MFT_BITMAP == MFT_BITMAP || mii.fType&MFT_SEPARATOR == MFT_SEPARATOR {
 		return false
 	}
 	mii.fMask |= MIIM_STRING | MIIM_FTYPE
 	mii.fType |= MFT_STRING
-	mii.dwTypeData = syscall.StringToUTF16Ptr(str)
+	mii.dwTypeData = labelUTF16(str)
 	return true
 }
 
+// labelUTF16 converts item text to a NUL-terminated UTF-16 string. Windows
+// stores item text without a length limit and keeps surrogate pairs,
+// combining characters, and bidirectional marks as they are, so such text
+// reads back unchanged. Two cases cannot round-trip: invalid UTF-8 is replaced
+// with U+FFFD, and text after a NUL character is dropped, as Windows would
+// stop there anyway.
+func labelUTF16(s string) *uint16 {
+	if i := strings.IndexByte(s, 0); i >= 0 {
+		s = s[:i]
+	}
+	return &utf16.Encode([]rune(s + "\x00"))[0]
+}
+
 // SetAsBitmap sets the masks and sets the handle to the given bitmap handle.
 func (mii *MenuItemInfo) SetAsBitmap(hbm HBitmap) (ok bool) {
 	if mii.fType&MFT_STRING == MFT_STRING || mii.fType&MFT_SEPARATOR == MFT_SEPARATOR {
@@ -266,6 +573,13 @@ func (mii *MenuItemInfo) SetAsBitmap(hbm HBitmap) (ok bool) {
 	return true
 }
 
+// SetItemBitmap sets the masks and sets the bitmap displayed next to the item
+// text to the given handle.
+func (mii *MenuItemInfo) SetItemBitmap(hbm HBitmap) {
+	mii.fMask |= MIIM_BITMAP
+	mii.hbmpItem = hbm
+}
+
 // SetAsSeparator sets the masks to be a separator.
 func (mii *MenuItemInfo) SetAsSeparator() (ok bool) {
 	if mii.fType&MFT_STRING == MFT_STRING || mii.fType&MFT_BITMAP == MFT_BITMAP {
@@ -356,12 +670,14 @@ func (mii *MenuItemInfo) SetRightOrder() {
 // CreateMenu creates a menu.
 // (https://docs.microsoft.com/en-us/windows/desktop/api/Winuser/nf-winuser-createmenu)
 func CreateMenu() (hMenu HMenu, ok bool) {
-	ret, _, _ := procCreateMenu.Call()
-	return HMenu(ret), ret != 0
+	ret, _, err := procCreateMenu.Call()
+	return HMenu(ret), check("CreateMenu", ret != 0, err)
 }
 
-// GetMenu returns the menu bar handle for the given window handle.
-func GetMenu(hwnd unsafe.Pointer) (hmenu HMenu, ok bool) {
+// GetMenu returns the menu bar handle for the given window handle. ok is false
+// if the window has no menu bar.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-getmenu)
+func GetMenu(hwnd HWnd) (hmenu HMenu, ok bool) {
 	ret, _, _ := procGetMenu.Call(uintptr(hwnd))
 	if ret == 0 {
 		return 0, false
@@ -369,21 +685,150 @@ func GetMenu(hwnd unsafe.Pointer) (hmenu HMenu, ok bool) {
 	return HMenu(ret), true
 }
 
-// SetMenu assigns a new menu to the specified window.
-func SetMenu(hwnd unsafe.Pointer, hmenu HMenu) (ok bool) {
-	ret, _, _ := procSetMenu.Call(uintptr(hwnd), uintptr(hmenu))
+// SetMenu assigns a new menu to the specified window. A zero hmenu removes the
+// window's menu bar. The menu previously assigned to the window is not
+// destroyed.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-setmenu)
+func SetMenu(hwnd HWnd, hmenu HMenu) (ok bool) {
+	ret, _, err := procSetMenu.Call(uintptr(hwnd), uintptr(hmenu))
+	return check("SetMenu", ret != 0, err)
+}
+
+// DrawMenuBar redraws the menu bar of the specified window. Call it after
+// changing the items of a menu bar that is already assigned to a window.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-drawmenubar)
+func DrawMenuBar(hwnd HWnd) (ok bool) {
+	ret, _, err := procDrawMenuBar.Call(uintptr(hwnd))
+	return check("DrawMenuBar", ret != 0, err)
+}
+
+// HiliteMenuItem adds or removes the highlight of an item in the menu bar of
+// hwnd. flags combines MF_HILITE or MF_UNHILITE with MF_BYCOMMAND or
+// MF_BYPOSITION. It returns whether the item is in the requested state
+// afterwards.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-hilitemenuitem)
+func HiliteMenuItem(hwnd HWnd, hmenu HMenu, item uint32, flags MenuFlag) (ok bool) {
+	ret, _, _ := procHiliteMenuItem.Call(uintptr(hwnd), uintptr(hmenu), uintptr(item), uintptr(flags))
 	return ret != 0
 }
 
+// CheckMarkDimensions returns the width and height in pixels of the default
+// check mark bitmap. Bitmaps for SetItemBitmaps, SetCheckmark, and
+// SetUncheckmark should have this size, or they are cropped or padded.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-getmenucheckmarkdimensions)
+func CheckMarkDimensions() (cx, cy int32) {
+	ret, _, _ := procGetMenuCheckMarkDimensions.Call()
+	return int32(ret & 0xFFFF), int32(ret >> 16 & 0xFFFF)
+}
+
+// ObjectID identifies the menu GetMenuBarInfo reports on.
+type ObjectID int32
+
+// Menus of a window.
+const (
+	// The window menu, opened from the icon in the title bar.
+	OBJID_SYSMENU ObjectID = -1
+	// The menu bar.
+	OBJID_MENU ObjectID = -3
+	// The popup menu open in the window, for menu windows of class #32768.
+	OBJID_CLIENT ObjectID = -4
+)
+
+// MenuBarInfo contains information about a menu bar.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/ns-winuser-menubarinfo)
+type MenuBarInfo struct {
+	// The size of the structure, in bytes.
+	cbSize uint32 // set by GetMenuBarInfo
+	// The screen rectangle of the menu bar, or of the item if one was given.
+	Bar Rect
+	// The menu of the menu bar, or of the popup menu.
+	Menu HMenu
+	// The submenu window, if a submenu is open.
+	MenuWindow HWnd
+	flags      uint32
+}
+
+// BarFocused reports whether the menu bar has the keyboard focus.
+func (mbi *MenuBarInfo) BarFocused() bool {
+	return mbi.flags&1 != 0
+}
+
+// Focused reports whether the menu item has the keyboard focus.
+func (mbi *MenuBarInfo) Focused() bool {
+	return mbi.flags&2 != 0
+}
+
+// GetMenuBarInfo retrieves information about a menu of hwnd. idItem is the
+// one-based position of the item to report on, or zero for the menu itself.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-getmenubarinfo)
+func GetMenuBarInfo(hwnd HWnd, idObject ObjectID, idItem int32) (info MenuBarInfo, ok bool) {
+	info.cbSize = uint32(unsafe.Sizeof(info))
+	ret, _, err := procGetMenuBarInfo.Call(uintptr(hwnd), uintptr(idObject), uintptr(idItem), uintptr(unsafe.Pointer(&info)))
+	return info, check("GetMenuBarInfo", ret != 0, err)
+}
+
 // CreatePopupMenu creates a drop-down menu, submenu, or shortcut menu.
 func CreatePopupMenu() (hmenu HMenu, ok bool) {
-	ret, _, _ := procCreatePopupMenu.Call()
-	if ret == 0 {
+	ret, _, err := procCreatePopupMenu.Call()
+	if !check("CreatePopupMenu", ret != 0, err) {
 		return 0, false
 	}
 	return HMenu(ret), true
 }
 
+// AppendMenu appends a new item to the end of the menu. id is the command ID
+// of the item, or the HMenu of the submenu if flags contains MF_POPUP. text is
+// the item text, converted as by SetAsString, and is ignored for separators.
+// Use InsertMenuItem for bitmap and owner-drawn items.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-appendmenuw)
+func (hMenu HMenu) AppendMenu(flags MenuFlag, id uintptr, text string) (ok bool) {
+	var lpNewItem uintptr
+	if flags&(MF_SEPARATOR|MF_BITMAP|MF_OWNERDRAW) == 0 {
+		lpNewItem = uintptr(unsafe.Pointer(labelUTF16(text)))
+	}
+	ret, _, err := procAppendMenu.Call(uintptr(hMenu), uintptr(flags), id, lpNewItem)
+	return check("AppendMenu", ret != 0, err)
+}
+
+// DeleteMenu deletes an item from the menu. If the item opens a submenu, the
+// submenu is destroyed as well; use RemoveMenu to keep it.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-deletemenu)
+func (hMenu HMenu) DeleteMenu(position uint32, byPosition bool) (ok bool) {
+	flags := MF_BYCOMMAND
+	if byPosition {
+		flags = MF_BYPOSITION
+	}
+	ret, _, err := procDeleteMenu.Call(uintptr(hMenu), uintptr(position), uintptr(flags))
+	return check("DeleteMenu", ret != 0, err)
+}
+
+// RemoveMenu removes an item from the menu without destroying the submenu it
+// opens, if any, so the submenu can be attached elsewhere. Get the submenu
+// handle before removing the item; the caller becomes responsible for
+// destroying it.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-removemenu)
+func (hMenu HMenu) RemoveMenu(position uint32, byPosition bool) (ok bool) {
+	flags := MF_BYCOMMAND
+	if byPosition {
+		flags = MF_BYPOSITION
+	}
+	ret, _, err := procRemoveMenu.Call(uintptr(hMenu), uintptr(position), uintptr(flags))
+	return check("RemoveMenu", ret != 0, err)
+}
+
+// Destroy destroys the menu and frees the memory it occupies, along with any
+// submenus it contains.
+//
+// A menu assigned to a window with SetMenu is destroyed automatically when the
+// window is destroyed, and submenus are destroyed with their parent menu. Call
+// Destroy for every other menu, such as shortcut menus that are not attached to
+// a menu bar or a menu bar replaced by another call to SetMenu.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-destroymenu)
+func (hMenu HMenu) Destroy() (ok bool) {
+	ret, _, err := procDestroyMenu.Call(uintptr(hMenu))
+	return check("DestroyMenu", ret != 0, err)
+}
+
 // InsertMenuItem inserts a new menu item at the specified position in a menu.
 // (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-insertmenuitemw)
 func (hMenu HMenu) InsertMenuItem(item uint32, fByPosition bool, lpmi *MenuItemInfo) (ok bool) {
@@ -392,6 +837,305 @@ func (hMenu HMenu) InsertMenuItem(item uint32, fByPosition bool, lpmi *MenuItemI
 		byPos = 1
 	}
 	lpmi.cbSize = uint32(unsafe.Sizeof(*lpmi))
-	ret, _, _ := procInsertMenuItem.Call(uintptr(hMenu), uintptr(item), uintptr(byPos), uintptr(unsafe.Pointer(lpmi)))
-	return ret != 0
+	ret, _, err := procInsertMenuItem.Call(uintptr(hMenu), uintptr(item), uintptr(byPos), uintptr(unsafe.Pointer(lpmi)))
+	return check("InsertMenuItem", ret != 0, err)
+}
+
+// SetMenuItemInfo changes information about a menu item in place, such as its
+// text, state, or bitmap. Only the members selected by the masks of lpmi are
+// changed.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-setmenuiteminfow)
+func (hMenu HMenu) SetMenuItemInfo(item uint32, fByPosition bool, lpmi *MenuItemInfo) (ok bool) {
+	byPos := 0
+	if fByPosition {
+		byPos = 1
+	}
+	lpmi.cbSize = uint32(unsafe.Sizeof(*lpmi))
+	ret, _, err := procSetMenuItemInfo.Call(uintptr(hMenu), uintptr(item), uintptr(byPos), uintptr(unsafe.Pointer(lpmi)))
+	return check("SetMenuItemInfo", ret != 0, err)
+}
+
+// GetMenuItemInfo retrieves information about a menu item. Select the members
+// to retrieve with the masks of lpmi, for example with SetMask(MIIM_STRING |
+// MIIM_FTYPE | MIIM_STATE), then read them with the getters. The item text is
+// copied into a buffer sized to fit, so lpmi can be passed to InsertMenuItem or
+// SetMenuItemInfo afterwards.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-getmenuiteminfow)
+func (hMenu HMenu) GetMenuItemInfo(item uint32, fByPosition bool, lpmi *MenuItemInfo) (ok bool) {
+	byPos := 0
+	if fByPosition {
+		byPos = 1
+	}
+	lpmi.cbSize = uint32(unsafe.Sizeof(*lpmi))
+	wantText := lpmi.fMask&(MIIM_STRING|MIIM_TYPE) != 0
+	if wantText {
+		// Query the text length first.
+		lpmi.dwTypeData = nil
+		lpmi.cch = 0
+	}
+	ret, _, err := procGetMenuItemInfo.Call(uintptr(hMenu), uintptr(item), uintptr(byPos), uintptr(unsafe.Pointer(lpmi)))
+	if !check("GetMenuItemInfo", ret != 0, err) {
+		return false
+	}
+	if !wantText {
+		return true
+	}
+	if !lpmi.isString() {
+		// With MIIM_TYPE, dwTypeData holds a handle or application value.
+		lpmi.dwTypeData = nil
+		return true
+	}
+	buf := make([]uint16, lpmi.cch+1)
+	lpmi.dwTypeData = &buf[0]
+	lpmi.cch = uint32(len(buf))
+	ret, _, err = procGetMenuItemInfo.Call(uintptr(hMenu), uintptr(item), uintptr(byPos), uintptr(unsafe.Pointer(lpmi)))
+	return check("GetMenuItemInfo", ret != 0, err)
+}
+
+// ItemID returns the command ID of the menu item at the given zero-based
+// position. ok is false if the item opens a submenu, which has no command ID,
+// or if there is no item at that position.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-getmenuitemid)
+func (hMenu HMenu) ItemID(pos uint32) (id uint32, ok bool) {
+	ret, _, _ := procGetMenuItemID.Call(uintptr(hMenu), uintptr(pos))
+	// GetMenuItemID returns -1 as a UINT on failure.
+	if uint32(ret) == ^uint32(0) {
+		return 0, false
+	}
+	return uint32(ret), true
+}
+
+// SubMenu returns the submenu opened by the menu item at the given zero-based
+// position. ok is false if the item does not open a submenu.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-getsubmenu)
+func (hMenu HMenu) SubMenu(pos uint32) (hmenu HMenu, ok bool) {
+	ret, _, _ := procGetSubMenu.Call(uintptr(hMenu), uintptr(pos))
+	return HMenu(ret), ret != 0
+}
+
+// EnableItem enables, disables, or grays the menu item. state is one of
+// MF_ENABLED, MF_DISABLED, or MF_GRAYED. It returns the previous state of the
+// item, or ok false if the item does not exist.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-enablemenuitem)
+func (hMenu HMenu) EnableItem(id uint32, byPosition bool, state MenuFlag) (prev MenuFlag, ok bool) {
+	flags := state | MF_BYCOMMAND
+	if byPosition {
+		flags = state | MF_BYPOSITION
+	}
+	ret, _, _ := procEnableMenuItem.Call(uintptr(hMenu), uintptr(id), uintptr(flags))
+	// EnableMenuItem returns -1 as a BOOL if the item does not exist.
+	if int32(ret) == -1 {
+		return 0, check("EnableMenuItem", false, errNoItem)
+	}
+	return MenuFlag(ret), true
+}
+
+// CheckItem sets or clears the check mark of the menu item. It returns whether
+// the item was checked before, or ok false if the item does not exist.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-checkmenuitem)
+func (hMenu HMenu) CheckItem(id uint32, byPosition bool, checked bool) (prevChecked bool, ok bool) {
+	flags := MF_UNCHECKED
+	if checked {
+		flags = MF_CHECKED
+	}
+	if byPosition {
+		flags |= MF_BYPOSITION
+	}
+	ret, _, _ := procCheckMenuItem.Call(uintptr(hMenu), uintptr(id), uintptr(flags))
+	// CheckMenuItem returns -1 as a DWORD if the item does not exist.
+	if uint32(ret) == 0xFFFFFFFF {
+		return false, check("CheckMenuItem", false, errNoItem)
+	}
+	return MenuFlag(ret) == MF_CHECKED, true
+}
+
+// State returns the flags of the menu item, such as MF_CHECKED, MF_GRAYED,
+// MF_DISABLED, MF_HILITE, MF_DEFAULT, MF_SEPARATOR, or MF_POPUP. flags is
+// MF_BYCOMMAND or MF_BYPOSITION. For an item that opens a submenu, bits 8
+// through 15 of the result hold the number of items in the submenu instead of
+// flags. ok is false if the item does not exist.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-getmenustate)
+func (hMenu HMenu) State(id uint32, flags MenuFlag) (state MenuFlag, ok bool) {
+	ret, _, _ := procGetMenuState.Call(uintptr(hMenu), uintptr(id), uintptr(flags))
+	// GetMenuState returns -1 as a UINT if the item does not exist.
+	if uint32(ret) == 0xFFFFFFFF {
+		return 0, check("GetMenuState", false, errNoItem)
+	}
+	return MenuFlag(ret), true
+}
+
+// ItemString returns the text of the menu item, including any '&' mnemonic
+// markers and shortcut text after a tab. Items without text, such as
+// separators, have an empty string.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-getmenustringw)
+func (hMenu HMenu) ItemString(id uint32, byPosition bool) (string, error) {
+	flags := MF_BYCOMMAND
+	if byPosition {
+		flags = MF_BYPOSITION
+	}
+	n, _, _ := procGetMenuString.Call(uintptr(hMenu), uintptr(id), 0, 0, uintptr(flags))
+	if n == 0 {
+		// GetMenuString returns 0 both for empty text and for missing items.
+		// Probe without check, as a missing item is reported as errNoItem.
+		if state, _, _ := procGetMenuState.Call(uintptr(hMenu), uintptr(id), uintptr(flags)); uint32(state) == 0xFFFFFFFF {
+			return "", errNoItem
+		}
+		return "", nil
+	}
+	buf := make([]uint16, n+1)
+	n, _, err := procGetMenuString.Call(uintptr(hMenu), uintptr(id), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), uintptr(flags))
+	if !check("GetMenuString", n != 0, err) {
+		return "", err
+	}
+	return syscall.UTF16ToString(buf[:n]), nil
+}
+
+// SetDefaultItem makes the menu item the default item, which is drawn in bold
+// and is typically run when the user double-clicks a notification area icon.
+// A menu has at most one default item. Pass 0xFFFFFFFF with byPosition true to
+// clear the default item.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-setmenudefaultitem)
+func (hMenu HMenu) SetDefaultItem(item uint32, byPosition bool) (ok bool) {
+	byPos := 0
+	if byPosition {
+		byPos = 1
+	}
+	ret, _, err := procSetMenuDefaultItem.Call(uintptr(hMenu), uintptr(item), uintptr(byPos))
+	return check("SetMenuDefaultItem", ret != 0, err)
+}
+
+// DefaultItem returns the command ID, or the position if byPosition is true, of
+// the default item of the menu. ok is false if the menu has no default item.
+// By default, disabled items and submenus are not searched; change that with
+// GMDI_USEDISABLED and GMDI_GOINTOPOPUPS.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-getmenudefaultitem)
+func (hMenu HMenu) DefaultItem(byPosition bool, flags DefaultItemFlag) (item uint32, ok bool) {
+	byPos := 0
+	if byPosition {
+		byPos = 1
+	}
+	ret, _, _ := procGetMenuDefaultItem.Call(uintptr(hMenu), uintptr(byPos), uintptr(flags))
+	// GetMenuDefaultItem returns -1 as a UINT if there is no default item.
+	if uint32(ret) == 0xFFFFFFFF {
+		return 0, false
+	}
+	return uint32(ret), true
+}
+
+// SetItemBitmaps sets the bitmaps shown next to the menu item when it is
+// checked and when it is unchecked. A zero bitmap shows nothing in that state,
+// and zero for both restores the default check mark. The bitmaps should have
+// the size returned by CheckMarkDimensions and are not destroyed with the menu.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-setmenuitembitmaps)
+func (hMenu HMenu) SetItemBitmaps(id uint32, byPosition bool, checked, unchecked HBitmap) (ok bool) {
+	flags := MF_BYCOMMAND
+	if byPosition {
+		flags = MF_BYPOSITION
+	}
+	ret, _, err := procSetMenuItemBitmaps.Call(uintptr(hMenu), uintptr(id), uintptr(flags), uintptr(unchecked), uintptr(checked))
+	return check("SetMenuItemBitmaps", ret != 0, err)
+}
+
+// SetContextHelpID associates a help context ID with the menu, which the
+// application can look up when handling WM_HELP for it. All items of the menu
+// share the ID.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-setmenucontexthelpid)
+func (hMenu HMenu) SetContextHelpID(id uint32) (ok bool) {
+	ret, _, err := procSetMenuContextHelpId.Call(uintptr(hMenu), uintptr(id))
+	return check("SetMenuContextHelpId", ret != 0, err)
+}
+
+// ContextHelpID returns the help context ID associated with the menu, or zero
+// if there is none.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-getmenucontexthelpid)
+func (hMenu HMenu) ContextHelpID() uint32 {
+	ret, _, _ := procGetMenuContextHelpId.Call(uintptr(hMenu))
+	return uint32(ret)
+}
+
+// ItemRect returns the screen rectangle of the menu item at the given zero-based
+// position. hwnd is the window containing the menu: the owner window for a menu
+// bar, or zero for a popup menu, which must be open for the rectangle to be
+// available.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-getmenuitemrect)
+func (hMenu HMenu) ItemRect(hwnd HWnd, pos uint32) (rc Rect, ok bool) {
+	ret, _, err := procGetMenuItemRect.Call(uintptr(hwnd), uintptr(hMenu), uintptr(pos), uintptr(unsafe.Pointer(&rc)))
+	return rc, check("GetMenuItemRect", ret != 0, err)
+}
+
+// CalculatePopupWindowPosition returns where a popup of the given size would be
+// placed if shown at anchor with flags, such as the TPM_* alignment flags and
+// TPM_WORKAREA, moved as needed to stay on screen and, if exclude is not nil,
+// to avoid covering the exclusion rectangle, as TrackPopupEx does.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-calculatepopupwindowposition)
+func CalculatePopupWindowPosition(anchor Point, size Size, flags TrackFlag, exclude *Rect) (pos Rect, ok bool) {
+	ret, _, err := procCalculatePopupWindowPosition.Call(uintptr(unsafe.Pointer(&anchor)), uintptr(unsafe.Pointer(&size)),
+		uintptr(flags), uintptr(unsafe.Pointer(exclude)), uintptr(unsafe.Pointer(&pos)))
+	return pos, check("CalculatePopupWindowPosition", ret != 0, err)
+}
+
+// MenuItemFromPoint returns the zero-based position of the item of hmenu at the
+// given screen point. hwnd is the window containing the menu: the owner
+// window for a menu bar, or zero for a popup menu, which must be open. ok is
+// false if no item is at that point.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-menuitemfrompoint)
+func MenuItemFromPoint(hwnd HWnd, hmenu HMenu, pt Point) (pos uint32, ok bool) {
+	args := append([]uintptr{uintptr(hwnd), uintptr(hmenu)}, pt.args()...)
+	ret, _, _ := procMenuItemFromPoint.Call(args...)
+	if int32(ret) == -1 {
+		return 0, false
+	}
+	return uint32(ret), true
+}
+
+// TrackPopup displays the shortcut menu at the given screen coordinates and
+// tracks the selection of items on it. hwnd owns the menu and receives its
+// messages; for notification area menus, make it the foreground window first so
+// the menu closes when the user clicks elsewhere.
+//
+// If flags contains TPM_RETURNCMD, cmd is the identifier of the chosen item, or
+// zero if the menu was dismissed without a selection. Otherwise the selection is
+// posted to hwnd as WM_COMMAND and cmd is always zero.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-trackpopupmenu)
+func (hMenu HMenu) TrackPopup(flags TrackFlag, x, y int32, hwnd HWnd) (cmd uint32, ok bool) {
+	ret, _, err := procTrackPopupMenu.Call(uintptr(hMenu), uintptr(flags), uintptr(x), uintptr(y), 0,
+		uintptr(hwnd), 0)
+	return trackResult("TrackPopupMenu", flags, ret, err)
+}
+
+// TPMParams contains extended parameters for TrackPopupEx.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/ns-winuser-tpmparams)
+type TPMParams struct {
+	// The size of the structure, in bytes.
+	cbSize uint32 // set by TrackPopupEx
+	// The rectangle to exclude when positioning the window, in screen
+	// coordinates. The menu is placed next to it instead of covering it, which
+	// keeps the control the menu was launched from, such as a toolbar button,
+	// visible.
+	Exclude Rect
+}
+
+// TrackPopupEx is like TrackPopup, but avoids covering the exclusion rectangle
+// in params when positioning the menu. params may be nil. Use TPM_VERTICAL or
+// TPM_HORIZONTAL to choose which alignment the system tries to keep first when
+// moving the menu out of the way.
+// (https://docs.microsoft.com/en-us/windows/desktop/api/winuser/nf-winuser-trackpopupmenuex)
+func (hMenu HMenu) TrackPopupEx(flags TrackFlag, x, y int32, hwnd HWnd, params *TPMParams) (cmd uint32, ok bool) {
+	var lptpm uintptr
+	if params != nil {
+		params.cbSize = uint32(unsafe.Sizeof(*params))
+		lptpm = uintptr(unsafe.Pointer(params))
+	}
+	ret, _, err := procTrackPopupMenuEx.Call(uintptr(hMenu), uintptr(flags), uintptr(x), uintptr(y),
+		uintptr(hwnd), lptpm)
+	return trackResult("TrackPopupMenuEx", flags, ret, err)
+}
+
+func trackResult(api string, flags TrackFlag, ret uintptr, err error) (cmd uint32, ok bool) {
+	if flags&TPM_RETURNCMD == TPM_RETURNCMD {
+		// Zero is both "no selection" and failure; only the latter sets the
+		// last error.
+		return uint32(ret), check(api, ret != 0 || err == syscall.Errno(0), err)
+	}
+	return 0, check(api, ret != 0, err)
 }